@@ -22,6 +22,13 @@ type (
 		Node
 		expressionNode()
 	}
+
+	// Pattern is matched against a value, binding the names in it to the
+	// parts of the value they stand for.
+	Pattern interface {
+		Node
+		patternNode()
+	}
 )
 
 type Program struct {
@@ -50,13 +57,17 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode()      {}
+func (i *Identifier) patternNode()         {} // binds the value, unless it is _
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
+// LetStatement is let Name = Value;, or const Name = Value; with Const set,
+// which binds a name that nothing in its scope may bind again.
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
 	Value Expression
+	Const bool
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -74,6 +85,30 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// LetPatternStatement is let Pattern = Value, which binds the names in an
+// array or hash pattern to the parts of Value they stand for. A Value that
+// does not match is an error.
+type LetPatternStatement struct {
+	Token   token.Token // the token.LET token
+	Pattern Pattern
+	Value   Expression
+}
+
+func (lp *LetPatternStatement) statementNode()       {}
+func (lp *LetPatternStatement) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LetPatternStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(lp.TokenLiteral() + " ")
+	out.WriteString(lp.Pattern.String())
+	out.WriteString(" = ")
+
+	if lp.Value != nil {
+		out.WriteString(lp.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
@@ -110,6 +145,7 @@ func (es *ExpressionStatement) String() string {
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token // the closing }, unset in blocks without braces
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -178,10 +214,13 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+// IfExpression is if (Condition) { Consequence }, followed by any number of
+// else if clauses, ElseIfs, tried in order, and an optional else { Alternative }.
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
 	Consequence *BlockStatement
+	ElseIfs     []*ElseIf
 	Alternative *BlockStatement
 }
 
@@ -194,6 +233,10 @@ func (ie *IfExpression) String() string {
 	out.WriteString(ie.Condition.String())
 	out.WriteString(" ")
 	out.WriteString(ie.Consequence.String())
+	for _, ei := range ie.ElseIfs {
+		out.WriteString("else ")
+		out.WriteString(ei.String())
+	}
 	if ie.Alternative != nil {
 		out.WriteString("else ")
 		out.WriteString(ie.Alternative.String())
@@ -201,9 +244,401 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ElseIf is else if (Condition) { Consequence } in an if expression.
+type ElseIf struct {
+	Token       token.Token // the token.IF token
+	Condition   Expression
+	Consequence *BlockStatement
+}
+
+func (ei *ElseIf) TokenLiteral() string { return ei.Token.Literal }
+func (ei *ElseIf) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("if")
+	out.WriteString(ei.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ei.Consequence.String())
+	return out.String()
+}
+
+// ConditionalExpression is Condition ? Consequence : Alternative.
+type ConditionalExpression struct {
+	Token       token.Token // the token.QUESTION token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+// TryExpression is try { Block } catch (Param) { Catch } finally { Finally }.
+// Either the catch or the finally clause may be missing, not both. Its value
+// is that of Block, or of Catch when Block throws.
+type TryExpression struct {
+	Token   token.Token // the token.TRY token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+	if te.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(te.Param.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+	return out.String()
+}
+
+// ThrowStatement is throw Value;
+type ThrowStatement struct {
+	Token token.Token // the token.THROW token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("throw ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// DeferStatement is defer Call; the function and arguments are evaluated
+// when it runs, the call is made when the function it is in returns.
+type DeferStatement struct {
+	Token token.Token // the token.DEFER token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("defer ")
+	if ds.Call != nil {
+		out.WriteString(ds.Call.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// SpawnExpression is spawn Call, which evaluates the function and arguments
+// of Call and then makes the call in a task of its own, evaluating to the
+// task.
+type SpawnExpression struct {
+	Token token.Token // the token.SPAWN token
+	Call  *CallExpression
+}
+
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("spawn ")
+	if se.Call != nil {
+		out.WriteString(se.Call.String())
+	}
+	return out.String()
+}
+
+// SelectExpression is select { Cases default { Default } }. It waits until
+// the channel operation of one of its cases can go on, makes it and
+// evaluates to the value of the body of that case; with a default it does
+// not wait.
+type SelectExpression struct {
+	Token   token.Token // the token.SELECT token
+	Cases   []*SelectCase
+	Default *BlockStatement
+	Rbrace  token.Token // the closing } token
+}
+
+func (se *SelectExpression) expressionNode()      {}
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("select { ")
+	for _, c := range se.Cases {
+		out.WriteString(c.String())
+		out.WriteString(" ")
+	}
+	if se.Default != nil {
+		out.WriteString("default ")
+		out.WriteString(se.Default.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// SelectCase is case Variable = recv(ch) { Body }, case recv(ch) { Body } or
+// case send(ch, value) { Body } in a select; Call is the recv or send.
+type SelectCase struct {
+	Token    token.Token // the token.CASE token
+	Variable *Identifier
+	Call     *CallExpression
+	Body     *BlockStatement
+}
+
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SelectCase) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("case ")
+	if sc.Variable != nil {
+		out.WriteString(sc.Variable.String())
+		out.WriteString(" = ")
+	}
+	if sc.Call != nil {
+		out.WriteString(sc.Call.String())
+	}
+	out.WriteString(" ")
+	out.WriteString(sc.Body.String())
+	return out.String()
+}
+
+// Send reports whether the case sends rather than receives.
+func (sc *SelectCase) Send() bool {
+	fn, ok := sc.Call.Function.(*Identifier)
+	return ok && fn.Value == "send"
+}
+
+// MatchExpression is match (Subject) { Arms }. It evaluates to the body of
+// the first arm whose pattern matches the subject and whose guard holds.
+type MatchExpression struct {
+	Token   token.Token // the token.MATCH token
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Token // the closing } token
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, a := range me.Arms {
+		arms = append(arms, a.String())
+	}
+	out.WriteString("match (")
+	if me.Subject != nil {
+		out.WriteString(me.Subject.String())
+	}
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
+
+// MatchArm is Pattern if Guard => Body in a match; Guard may be nil.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Pattern.TokenLiteral() }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+// LiteralPattern matches a value equal to Value, an integer, string or
+// boolean literal, and of the same type.
+type LiteralPattern struct {
+	Token token.Token // the first token of Value
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// ArrayPattern is [Elements, ..Rest]. It matches an array of as many
+// elements as Elements, or of at least as many with a Rest, which gets an
+// array of the others.
+type ArrayPattern struct {
+	Token    token.Token // the token.LBRACKET token
+	Elements []Pattern
+	Rest     *Identifier
+	Rbracket token.Token // the closing ] token
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, ".."+ap.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern is {Keys[0]: Values[0], ...}. It matches a hash having every
+// key, whose values match; a key written as a name is that name as a string,
+// and name alone stands for name: name.
+type HashPattern struct {
+	Token  token.Token // the token.LBRACE token
+	Keys   []Expression
+	Values []Pattern
+	Rbrace token.Token // the closing } token
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for i, k := range hp.Keys {
+		pairs = append(pairs, k.String()+": "+hp.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// DefaultPattern is Pattern = Default, an element of an array or hash
+// pattern that may be missing: Pattern then matches the value of Default
+// instead. The elements of an array pattern with a default come last.
+type DefaultPattern struct {
+	Token   token.Token // the token.ASSIGN token
+	Pattern Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) patternNode()         {}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) String() string {
+	return dp.Pattern.String() + " = " + dp.Default.String()
+}
+
+// Required returns how many of elements, those of an array or hash
+// pattern, have no default.
+func Required(elements []Pattern) int {
+	n := 0
+	for _, e := range elements {
+		if _, ok := e.(*DefaultPattern); !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Bindings returns the names pattern binds, in order.
+func Bindings(pattern Pattern) []*Identifier {
+	names := []*Identifier{}
+	Inspect(pattern, func(node Node) bool {
+		// the keys of hash patterns and literals hold no identifiers, but
+		// defaults may use some
+		if n, ok := node.(*DefaultPattern); ok {
+			names = append(names, Bindings(n.Pattern)...)
+			return false
+		}
+		if n, ok := node.(*Identifier); ok && n.Value != "_" {
+			names = append(names, n)
+		}
+		return true
+	})
+	return names
+}
+
+// YieldExpression is yield Value, which hands Value to the consumer of the
+// generator it is in and evaluates to null once the generator resumes.
+type YieldExpression struct {
+	Token token.Token // the token.YIELD token
+	Value Expression
+}
+
+func (ye *YieldExpression) expressionNode()      {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("yield ")
+	if ye.Value != nil {
+		out.WriteString(ye.Value.String())
+	}
+	return out.String()
+}
+
+// ForExpression is for (Variable in Iterable) { Body }, which runs Body with
+// Variable bound to each element of Iterable in turn. Its value is null.
+type ForExpression struct {
+	Token    token.Token // the token.FOR token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode()      {}
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fe.Variable.String())
+	out.WriteString(" in ")
+	if fe.Iterable != nil {
+		out.WriteString(fe.Iterable.String())
+	}
+	out.WriteString(") ")
+	out.WriteString(fe.Body.String())
+	return out.String()
+}
+
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	// Defaults holds the default of each parameter that has one, nil for
+	// the others; it is nil if none has. The parameters with one come
+	// after those without.
+	Defaults []Expression
+	// Rest is set if the last parameter is ...name, which gets an array of
+	// the positional arguments beyond the others
+	Rest bool
 	Body       *BlockStatement
 	Name string
 }
@@ -219,8 +654,14 @@ func (fl *FunctionLiteral) String() string {
 	}
 	out.WriteString("(")
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		param := p.String()
+		if fl.Rest && i == len(fl.Parameters)-1 {
+			param = "..." + param
+		} else if d := fl.Default(i); d != nil {
+			param += " = " + d.String()
+		}
+		params = append(params, param)
 	}
 	out.WriteString(strings.Join(params, ","))
 	out.WriteString(")")
@@ -228,10 +669,58 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// Default returns the default of parameter i, or nil if it has none.
+func (fl *FunctionLiteral) Default(i int) Expression {
+	if i < len(fl.Defaults) {
+		return fl.Defaults[i]
+	}
+	return nil
+}
+
+// IsGenerator reports whether fl yields, which makes calling it return a
+// generator. Yields in the functions fl defines do not count.
+func (fl *FunctionLiteral) IsGenerator() bool {
+	yields := false
+	Inspect(fl.Body, func(node Node) bool {
+		switch node.(type) {
+		case *YieldExpression:
+			yields = true
+		case *FunctionLiteral:
+			return false
+		}
+		return !yields
+	})
+	return yields
+}
+
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ","))
+	out.WriteString(")")
+	out.WriteString(ml.Body.String())
+	return out.String()
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token // the closing ), unset in calls made by |>
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -250,6 +739,29 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// SpreadExpression is ...Value in the arguments of a call, which passes the
+// elements of the array Value as positional arguments.
+type SpreadExpression struct {
+	Token token.Token // the token.ELLIPSIS token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// KeywordArgument is Name: Value in the arguments of a call, which passes
+// Value to the parameter called Name. Keyword arguments come last.
+type KeywordArgument struct {
+	Token token.Token // the token.IDENT token of Name
+	Name  string
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()      {}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) String() string       { return ka.Name + ": " + ka.Value.String() }
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -259,9 +771,35 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// InterpolatedString is a string with embedded ${} expressions; Parts are
+// StringLiterals for the text and the expressions in between, whose values
+// are converted to strings and concatenated.
+type InterpolatedString struct {
+	Token token.Token // the token.TEMPLATE token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(text.Value)
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
+	Rbracket token.Token // the closing ] token
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
@@ -279,10 +817,15 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// IndexExpression is Left[Index]. With Optional set it is Left?[Index],
+// which is null when Left is null, as is the rest of the chain of indexes,
+// slices, selectors and calls applied to it.
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool
+	Rbracket token.Token // the closing ]
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -292,15 +835,53 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 	return out.String()
 }
 
+// SliceExpression is left[start:end:step]; Start, End and Step may be nil.
+type SliceExpression struct {
+	Token    token.Token // the '[' token
+	Left     Expression
+	Start    Expression
+	End      Expression
+	Step     Expression
+	Rbracket token.Token // the closing ] token
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
 type HashLiteral struct {
-	Token token.Token
-	Paris map[Expression]Expression
+	Token  token.Token
+	Paris  map[Expression]Expression
+	Keys   []Expression // keys of Paris in source order
+	Rbrace token.Token  // the closing } token
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -309,8 +890,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for k,v := range hl.Paris{
-		pairs = append(pairs, k.String()+":"+v.String())
+	for _, k := range hl.Keys {
+		pairs = append(pairs, k.String()+":"+hl.Paris[k].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs,", "))
@@ -323,6 +904,7 @@ type StructDeclarion struct{
 	Name string
 	Methods []*FunctionLiteral
 	Vars []*Identifier
+	Rbrace token.Token // the closing } token
 }
 
 func (sd *StructDeclarion) statementNode(){}
@@ -355,4 +937,63 @@ func (fds *FunctionDeclarionStatement) String() string{
 	
 	out.WriteString(fds.Body.String())
 	return out.String()
-}
\ No newline at end of file
+}
+// ImportStatement is `import "path" as name;` or
+// `import { a, b } from "path";`.
+type ImportStatement struct {
+	Token     token.Token
+	Path      string
+	PathToken token.Token   // the token.STRING token of Path
+	Alias     *Identifier   // nil for selective imports
+	Names     []*Identifier // names bound by a selective import
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	if is.Alias != nil {
+		out.WriteString(fmt.Sprintf("%q as %s", is.Path, is.Alias.String()))
+	} else {
+		names := []string{}
+		for _, n := range is.Names {
+			names = append(names, n.String())
+		}
+		out.WriteString("{" + strings.Join(names, ", ") + "} from ")
+		out.WriteString(fmt.Sprintf("%q", is.Path))
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// ExportStatement is `export let name = value;` or `export const name =
+// value;`. Only exported names can be reached from modules importing this
+// one.
+type ExportStatement struct {
+	Token     token.Token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// SelectorExpression is `left.name`, used to reach the exports of an
+// imported module, and otherwise left["name"]. With Optional set it is
+// `left?.name`, null when left is null like Left?[Index].
+type SelectorExpression struct {
+	Token    token.Token // the '.' or '?.' token
+	Left     Expression
+	Name     *Identifier
+	Optional bool
+}
+
+func (se *SelectorExpression) expressionNode()      {}
+func (se *SelectorExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectorExpression) String() string {
+	return "(" + se.Left.String() + se.Token.Literal + se.Name.String() + ")"
+}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"gwine/token"
+	"strings"
 	"testing"
 )
 
@@ -27,4 +28,189 @@ func TestString(t *testing.T) {
 		t.Fatalf("string fatal")
 	}
 
-}
\ No newline at end of file
+}
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+func integer(v int64, lit string) *IntegerLiteral {
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: lit}, Value: v}
+}
+func str(v string) *StringLiteral {
+	return &StringLiteral{Token: token.Token{Type: token.STRING, Literal: v}, Value: v}
+}
+
+// let a = x + 1; {"b": y, "a": 2}; fn f(p) { p }
+func testProgram() *Program {
+	kb, ka := str("b"), str("a")
+	return &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  ident("a"),
+				Value: &InfixExpression{Left: ident("x"), Operator: "+", Right: integer(1, "1")},
+			},
+			&ExpressionStatement{
+				Expression: &HashLiteral{
+					Paris: map[Expression]Expression{kb: ident("y"), ka: integer(2, "2")},
+					Keys:  []Expression{kb, ka},
+				},
+			},
+			&FunctionDeclarionStatement{
+				Name: "f",
+				Body: &FunctionLiteral{
+					Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+					Name:       "f",
+					Parameters: []*Identifier{ident("p")},
+					Body: &BlockStatement{
+						Statements: []Statement{&ExpressionStatement{Expression: ident("p")}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestInspect(t *testing.T) {
+	names := []string{}
+	Inspect(testProgram(), func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			names = append(names, id.Value)
+		}
+		return true
+	})
+	if strings.Join(names, ",") != "a,x,y,p,p" {
+		t.Fatalf("identifiers visited in wrong order,got %v", names)
+	}
+
+	// returning false skips the children
+	count := 0
+	Inspect(testProgram(), func(n Node) bool {
+		if n != nil {
+			count++
+		}
+		_, isLet := n.(*LetStatement)
+		return !isLet
+	})
+	if count != 14 {
+		t.Fatalf("expected 14 nodes,got %v", count)
+	}
+}
+
+func TestHashLiteralOrder(t *testing.T) {
+	pg := testProgram()
+	for i := 0; i < 10; i++ {
+		if pg.Statements[1].String() != "{b:y, a:2}" {
+			t.Fatalf("hash literal not in source order,got %v", pg.Statements[1].String())
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	pg := testProgram()
+	before := pg.String()
+
+	rewritten := Rewrite(pg, nil, func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *Identifier:
+			if n.Value == "x" || n.Value == "y" {
+				c.Replace(integer(10, "10"))
+			}
+		case *ExpressionStatement:
+			if _, ok := n.Expression.(*HashLiteral); ok && c.Name() == "Statements" {
+				c.Delete()
+			}
+		}
+		return true
+	})
+
+	if pg.String() != before {
+		t.Fatalf("original tree modified,got %v", pg.String())
+	}
+	got := rewritten.(*Program)
+	if len(got.Statements) != 2 || got.Statements[0].String() != "let a = (10 + 1);" {
+		t.Fatalf("rewrite wrong,got %v", got.String())
+	}
+	// untouched subtrees are shared with the original
+	if got.Statements[1] != pg.Statements[2] {
+		t.Fatalf("unchanged function declaration was copied")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	pg := testProgram()
+	data, err := Marshal(pg)
+	if err != nil {
+		t.Fatalf("marshal fail %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"kind":"Program","pos":`) {
+		t.Fatalf("kind should come first,got %s", data)
+	}
+
+	node, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal fail %v", err)
+	}
+	if node.String() != pg.String() {
+		t.Fatalf("round trip wrong,expected %v,got %v", pg.String(), node.String())
+	}
+
+	var decoded Program
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("unmarshal fail %v", err)
+	}
+	again, _ := decoded.MarshalJSON()
+	if string(again) != string(data) {
+		t.Fatalf("second encoding differs\n%s\n%s", data, again)
+	}
+
+	if _, err := Unmarshal([]byte(`{"kind":"Nope"}`)); err == nil {
+		t.Fatalf("expected error for unknown kind")
+	}
+	if _, err := Unmarshal([]byte(`{"kind":"Program","statements":[{"kind":"Identifier","name":"x"}]}`)); err == nil {
+		t.Fatalf("expected error for expression in statement list")
+	}
+}
+
+func TestJSONMissingChildren(t *testing.T) {
+	stmt := func(exp string) string {
+		return `{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":` + exp + `}]}`
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{stmt(`{"kind":"InfixExpression","operator":"+"}`), "ast: expected expression, got <nil>"},
+		{stmt(`{"kind":"InfixExpression","operator":"+","left":{"kind":"IntegerLiteral","value":1},"right":null}`), "ast: expected expression, got <nil>"},
+		{stmt(`{"kind":"CallExpression"}`), "ast: expected expression, got <nil>"},
+		{stmt(`{"kind":"IfExpression","condition":{"kind":"Boolean","value":true}}`), "ast: expected BlockStatement, got <nil>"},
+		{stmt(`{"kind":"ArrayLiteral","elements":[null]}`), "ast: expected expression, got <nil>"},
+		{stmt(`{"kind":"MatchExpression","subject":{"kind":"Identifier","name":"x"},"arms":[{"kind":"MatchArm","body":{"kind":"BlockStatement"}}]}`), "ast: expected pattern, got <nil>"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement"}]}`, "ast: expected expression, got <nil>"},
+		{`{"kind":"Program","statements":[{"kind":"DeferStatement"}]}`, "ast: expected CallExpression, got <nil>"},
+		{stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"},{"kind":"Identifier","name":"b"}],"defaults":[{"kind":"IntegerLiteral","value":5},null]}`), "ast: FunctionLiteral parameter b must have a default, following one that has"},
+		{stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"},{"kind":"Identifier","name":"b"}],"defaults":[{"kind":"IntegerLiteral","value":5}]}`), "ast: FunctionLiteral parameter b must have a default, following one that has"},
+		{stmt(`{"kind":"FunctionLiteral","parameters":[],"rest":true}`), "ast: FunctionLiteral rest without parameters"},
+		{stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"}],"defaults":[null,null]}`), "ast: FunctionLiteral has 2 defaults for 1 parameters"},
+		{stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"}],"defaults":[{"kind":"IntegerLiteral","value":1}],"rest":true}`), "ast: FunctionLiteral has 1 defaults for 0 parameters"},
+		{stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"},{"kind":"Identifier","name":"a"}]}`), "ast: FunctionLiteral duplicate parameter a"},
+	}
+	for i, tt := range tests {
+		_, err := Unmarshal([]byte(tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("test %v :expected error %q,got %v", i, tt.expected, err)
+		}
+	}
+
+	// optional children may be left out
+	for i, input := range []string{
+		stmt(`{"kind":"IfExpression","condition":{"kind":"Boolean","value":true},"consequence":{"kind":"BlockStatement"}}`),
+		stmt(`{"kind":"SliceExpression","left":{"kind":"Identifier","name":"a"}}`),
+		stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"},{"kind":"Identifier","name":"b"}],"defaults":[null,{"kind":"IntegerLiteral","value":1}]}`),
+		stmt(`{"kind":"FunctionLiteral","parameters":[{"kind":"Identifier","name":"a"},{"kind":"Identifier","name":"b"}],"defaults":[{"kind":"IntegerLiteral","value":1}],"rest":true}`),
+	} {
+		if _, err := Unmarshal([]byte(input)); err != nil {
+			t.Errorf("test %v :unmarshal fail %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,1312 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gwine/token"
+	"strconv"
+)
+
+// Marshal encodes node and all of its children as JSON. Every node becomes an
+// object with a "kind" tag naming its Go type, a "pos" holding its source
+// span and its fields; children are nested objects and lists keep source
+// order. The span gives the line and column of the first character of the
+// node and those just past its last character:
+//
+//	{"kind":"Identifier","pos":{"line":1,"column":5,"endLine":1,"endColumn":8},"name":"foo"}
+//
+// Lines and columns count from 1 and columns count bytes. Nodes built
+// without positions have zero spans.
+func Marshal(node Node) ([]byte, error) {
+	obj, err := encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// Unmarshal rebuilds a node encoded by Marshal. Tokens are synthesized from
+// the node kind and fields, so the result can be handed to the compiler or
+// the evaluator like the output of the parser.
+func Unmarshal(data []byte) (Node, error) {
+	return decode(json.RawMessage(data))
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return Marshal(p)
+}
+func (p *Program) UnmarshalJSON(data []byte) error {
+	node, err := Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return fmt.Errorf("ast: expected Program, got %T", node)
+	}
+	*p = *program
+	return nil
+}
+
+// StartToken returns the first token of node, which gives its source position.
+func StartToken(node Node) token.Token {
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) > 0 {
+			return StartToken(n.Statements[0])
+		}
+	case *Identifier:
+		return n.Token
+	case *LetStatement:
+		return n.Token
+	case *LetPatternStatement:
+		return n.Token
+	case *ReturnStatement:
+		return n.Token
+	case *ExpressionStatement:
+		return n.Token
+	case *BlockStatement:
+		return n.Token
+	case *IntegerLiteral:
+		return n.Token
+	case *PrefixExpression:
+		return n.Token
+	case *InfixExpression:
+		return StartToken(n.Left)
+	case *Boolean:
+		return n.Token
+	case *IfExpression:
+		return n.Token
+	case *ElseIf:
+		return n.Token
+	case *ConditionalExpression:
+		return StartToken(n.Condition)
+	case *TryExpression:
+		return n.Token
+	case *ThrowStatement:
+		return n.Token
+	case *DeferStatement:
+		return n.Token
+	case *SpawnExpression:
+		return n.Token
+	case *SelectExpression:
+		return n.Token
+	case *SelectCase:
+		return n.Token
+	case *MatchExpression:
+		return n.Token
+	case *MatchArm:
+		if n.Pattern != nil {
+			return StartToken(n.Pattern)
+		}
+	case *LiteralPattern:
+		return n.Token
+	case *ArrayPattern:
+		return n.Token
+	case *HashPattern:
+		return n.Token
+	case *DefaultPattern:
+		if n.Pattern != nil {
+			return StartToken(n.Pattern)
+		}
+	case *YieldExpression:
+		return n.Token
+	case *ForExpression:
+		return n.Token
+	case *FunctionLiteral:
+		return n.Token
+	case *MacroLiteral:
+		return n.Token
+	case *CallExpression:
+		// the function of a call made by |> comes after its first argument
+		start := StartToken(n.Function)
+		if len(n.Arguments) > 0 {
+			if first := StartToken(n.Arguments[0]); first.Line != 0 &&
+				(first.Line < start.Line || first.Line == start.Line && first.Column < start.Column) {
+				return first
+			}
+		}
+		return start
+	case *SpreadExpression:
+		return n.Token
+	case *KeywordArgument:
+		return n.Token
+	case *StringLiteral:
+		return n.Token
+	case *InterpolatedString:
+		return n.Token
+	case *ArrayLiteral:
+		return n.Token
+	case *IndexExpression:
+		return StartToken(n.Left)
+	case *SliceExpression:
+		return StartToken(n.Left)
+	case *HashLiteral:
+		return n.Token
+	case *StructDeclarion:
+		return n.Token
+	case *FunctionDeclarionStatement:
+		return n.Token
+	case *ImportStatement:
+		return n.Token
+	case *ExportStatement:
+		return n.Token
+	case *SelectorExpression:
+		return StartToken(n.Left)
+	}
+	return token.Token{}
+}
+
+// EndToken returns the last token of node, whose EndLine and EndColumn give
+// the end of its source span. Closing brackets not recorded in the tree, as
+// in nodes built by hand, are left out of the span.
+func EndToken(node Node) token.Token {
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) > 0 {
+			return EndToken(n.Statements[len(n.Statements)-1])
+		}
+	case *Identifier:
+		return n.Token
+	case *LetStatement:
+		return EndToken(n.Value)
+	case *LetPatternStatement:
+		return EndToken(n.Value)
+	case *ReturnStatement:
+		return EndToken(n.ReturnValue)
+	case *ExpressionStatement:
+		return EndToken(n.Expression)
+	case *BlockStatement:
+		if n.Rbrace.Type != "" || len(n.Statements) == 0 {
+			return n.Rbrace
+		}
+		return EndToken(n.Statements[len(n.Statements)-1])
+	case *IntegerLiteral:
+		return n.Token
+	case *PrefixExpression:
+		return EndToken(n.Right)
+	case *InfixExpression:
+		return EndToken(n.Right)
+	case *Boolean:
+		return n.Token
+	case *IfExpression:
+		if n.Alternative != nil {
+			return EndToken(n.Alternative)
+		}
+		if len(n.ElseIfs) > 0 {
+			return EndToken(n.ElseIfs[len(n.ElseIfs)-1])
+		}
+		return EndToken(nodeOrNil(n.Consequence))
+	case *ElseIf:
+		return EndToken(nodeOrNil(n.Consequence))
+	case *ConditionalExpression:
+		return EndToken(n.Alternative)
+	case *TryExpression:
+		if n.Finally != nil {
+			return EndToken(n.Finally)
+		}
+		if n.Catch != nil {
+			return EndToken(n.Catch)
+		}
+		return EndToken(nodeOrNil(n.Block))
+	case *ThrowStatement:
+		return EndToken(n.Value)
+	case *DeferStatement:
+		return EndToken(nodeOrNil(n.Call))
+	case *SpawnExpression:
+		return EndToken(nodeOrNil(n.Call))
+	case *SelectExpression:
+		return n.Rbrace
+	case *SelectCase:
+		return EndToken(nodeOrNil(n.Body))
+	case *MatchExpression:
+		return n.Rbrace
+	case *MatchArm:
+		return EndToken(nodeOrNil(n.Body))
+	case *LiteralPattern:
+		return EndToken(n.Value)
+	case *ArrayPattern:
+		return n.Rbracket
+	case *HashPattern:
+		return n.Rbrace
+	case *DefaultPattern:
+		return EndToken(n.Default)
+	case *YieldExpression:
+		return EndToken(n.Value)
+	case *ForExpression:
+		return EndToken(nodeOrNil(n.Body))
+	case *FunctionLiteral:
+		return EndToken(nodeOrNil(n.Body))
+	case *MacroLiteral:
+		return EndToken(nodeOrNil(n.Body))
+	case *CallExpression:
+		if n.Rparen.Type != "" {
+			return n.Rparen
+		}
+		end := EndToken(n.Function)
+		if len(n.Arguments) > 0 {
+			if last := EndToken(n.Arguments[len(n.Arguments)-1]); last.EndLine > end.EndLine ||
+				last.EndLine == end.EndLine && last.EndColumn > end.EndColumn {
+				return last
+			}
+		}
+		return end
+	case *SpreadExpression:
+		return EndToken(n.Value)
+	case *KeywordArgument:
+		return EndToken(n.Value)
+	case *StringLiteral:
+		return n.Token
+	case *InterpolatedString:
+		return n.Token
+	case *ArrayLiteral:
+		return n.Rbracket
+	case *IndexExpression:
+		if n.Rbracket.Type != "" {
+			return n.Rbracket
+		}
+		return EndToken(n.Index)
+	case *SliceExpression:
+		return n.Rbracket
+	case *HashLiteral:
+		return n.Rbrace
+	case *StructDeclarion:
+		return n.Rbrace
+	case *FunctionDeclarionStatement:
+		return EndToken(nodeOrNil(n.Body))
+	case *ImportStatement:
+		if n.Alias != nil {
+			return n.Alias.Token
+		}
+		return n.PathToken
+	case *ExportStatement:
+		return EndToken(nodeOrNil(n.Statement))
+	case *SelectorExpression:
+		return EndToken(nodeOrNil(n.Name))
+	}
+	return token.Token{}
+}
+
+type jsonPos struct {
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+// jsonObject keeps its fields in insertion order so that "kind" comes first.
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		name, _ := json.Marshal(f.name)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(name)
+		out.WriteString(":")
+		out.Write(value)
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+func encode(node Node) (interface{}, error) {
+	if node == nil {
+		return nil, nil
+	}
+	start, end := StartToken(node), EndToken(node)
+	obj := jsonObject{
+		{"kind", kindOf(node)},
+		{"pos", jsonPos{Line: start.Line, Column: start.Column, EndLine: end.EndLine, EndColumn: end.EndColumn}},
+	}
+	add := func(name string, value interface{}) {
+		obj = append(obj, jsonField{name, value})
+	}
+	var err error
+	child := func(name string, n Node) {
+		if err != nil {
+			return
+		}
+		var v interface{}
+		v, err = encode(n)
+		add(name, v)
+	}
+	children := func(name string, n int, at func(int) Node) {
+		list := make([]interface{}, 0, n)
+		for i := 0; i < n && err == nil; i++ {
+			var v interface{}
+			v, err = encode(at(i))
+			list = append(list, v)
+		}
+		add(name, list)
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		children("statements", len(n.Statements), func(i int) Node { return n.Statements[i] })
+	case *BlockStatement:
+		children("statements", len(n.Statements), func(i int) Node { return n.Statements[i] })
+	case *LetStatement:
+		child("name", nodeOrNil(n.Name))
+		child("value", n.Value)
+		if n.Const {
+			add("const", true)
+		}
+	case *LetPatternStatement:
+		child("pattern", n.Pattern)
+		child("value", n.Value)
+	case *ReturnStatement:
+		child("returnValue", n.ReturnValue)
+	case *ExpressionStatement:
+		child("expression", n.Expression)
+	case *Identifier:
+		add("name", n.Value)
+	case *IntegerLiteral:
+		add("value", n.Value)
+	case *StringLiteral:
+		add("value", n.Value)
+	case *Boolean:
+		add("value", n.Value)
+	case *PrefixExpression:
+		add("operator", n.Operator)
+		child("right", n.Right)
+	case *InfixExpression:
+		add("operator", n.Operator)
+		child("left", n.Left)
+		child("right", n.Right)
+	case *IfExpression:
+		child("condition", n.Condition)
+		child("consequence", nodeOrNil(n.Consequence))
+		if len(n.ElseIfs) > 0 {
+			children("elseIfs", len(n.ElseIfs), func(i int) Node { return n.ElseIfs[i] })
+		}
+		child("alternative", nodeOrNil(n.Alternative))
+	case *ElseIf:
+		child("condition", n.Condition)
+		child("consequence", nodeOrNil(n.Consequence))
+	case *ConditionalExpression:
+		child("condition", n.Condition)
+		child("consequence", n.Consequence)
+		child("alternative", n.Alternative)
+	case *TryExpression:
+		child("block", nodeOrNil(n.Block))
+		child("param", nodeOrNil(n.Param))
+		child("catch", nodeOrNil(n.Catch))
+		child("finally", nodeOrNil(n.Finally))
+	case *ThrowStatement:
+		child("value", n.Value)
+	case *DeferStatement:
+		child("call", nodeOrNil(n.Call))
+	case *SpawnExpression:
+		child("call", nodeOrNil(n.Call))
+	case *SelectExpression:
+		children("cases", len(n.Cases), func(i int) Node { return n.Cases[i] })
+		child("default", nodeOrNil(n.Default))
+	case *SelectCase:
+		child("variable", nodeOrNil(n.Variable))
+		child("call", nodeOrNil(n.Call))
+		child("body", nodeOrNil(n.Body))
+	case *MatchExpression:
+		child("subject", n.Subject)
+		children("arms", len(n.Arms), func(i int) Node { return n.Arms[i] })
+	case *MatchArm:
+		child("pattern", n.Pattern)
+		child("guard", n.Guard)
+		child("body", nodeOrNil(n.Body))
+	case *LiteralPattern:
+		child("value", n.Value)
+	case *ArrayPattern:
+		children("elements", len(n.Elements), func(i int) Node { return n.Elements[i] })
+		child("rest", nodeOrNil(n.Rest))
+	case *HashPattern:
+		pairs := make([]interface{}, 0, len(n.Keys))
+		for i, k := range n.Keys {
+			key, e := encode(k)
+			if e != nil {
+				return nil, e
+			}
+			value, e := encode(n.Values[i])
+			if e != nil {
+				return nil, e
+			}
+			pairs = append(pairs, jsonObject{{"key", key}, {"value", value}})
+		}
+		add("pairs", pairs)
+	case *DefaultPattern:
+		child("pattern", n.Pattern)
+		child("default", n.Default)
+	case *YieldExpression:
+		child("value", n.Value)
+	case *ForExpression:
+		child("variable", nodeOrNil(n.Variable))
+		child("iterable", n.Iterable)
+		child("body", nodeOrNil(n.Body))
+	case *FunctionLiteral:
+		add("name", n.Name)
+		children("parameters", len(n.Parameters), func(i int) Node { return n.Parameters[i] })
+		if n.Defaults != nil {
+			children("defaults", len(n.Defaults), func(i int) Node { return n.Defaults[i] })
+		}
+		if n.Rest {
+			add("rest", true)
+		}
+		child("body", nodeOrNil(n.Body))
+	case *MacroLiteral:
+		children("parameters", len(n.Parameters), func(i int) Node { return n.Parameters[i] })
+		child("body", nodeOrNil(n.Body))
+	case *CallExpression:
+		child("function", n.Function)
+		children("arguments", len(n.Arguments), func(i int) Node { return n.Arguments[i] })
+	case *SpreadExpression:
+		child("value", n.Value)
+	case *KeywordArgument:
+		add("name", n.Name)
+		child("value", n.Value)
+	case *ArrayLiteral:
+		children("elements", len(n.Elements), func(i int) Node { return n.Elements[i] })
+	case *InterpolatedString:
+		children("parts", len(n.Parts), func(i int) Node { return n.Parts[i] })
+	case *IndexExpression:
+		child("left", n.Left)
+		child("index", n.Index)
+		if n.Optional {
+			add("optional", true)
+		}
+	case *SliceExpression:
+		child("left", n.Left)
+		child("start", n.Start)
+		child("end", n.End)
+		child("step", n.Step)
+	case *HashLiteral:
+		pairs := make([]interface{}, 0, len(n.Keys))
+		for _, k := range n.Keys {
+			key, e := encode(k)
+			if e != nil {
+				return nil, e
+			}
+			value, e := encode(n.Paris[k])
+			if e != nil {
+				return nil, e
+			}
+			pairs = append(pairs, jsonObject{{"key", key}, {"value", value}})
+		}
+		add("pairs", pairs)
+	case *StructDeclarion:
+		add("name", n.Name)
+		children("vars", len(n.Vars), func(i int) Node { return n.Vars[i] })
+		children("methods", len(n.Methods), func(i int) Node { return n.Methods[i] })
+	case *FunctionDeclarionStatement:
+		add("name", n.Name)
+		child("body", nodeOrNil(n.Body))
+	case *ImportStatement:
+		add("path", n.Path)
+		child("alias", nodeOrNil(n.Alias))
+		children("names", len(n.Names), func(i int) Node { return n.Names[i] })
+	case *ExportStatement:
+		child("statement", nodeOrNil(n.Statement))
+	case *SelectorExpression:
+		child("left", n.Left)
+		child("name", nodeOrNil(n.Name))
+		if n.Optional {
+			add("optional", true)
+		}
+	default:
+		return nil, fmt.Errorf("ast: cannot encode %T", node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// nodeOrNil turns typed nil pointers into a nil Node.
+func nodeOrNil(n interface{}) Node {
+	switch n := n.(type) {
+	case *Identifier:
+		if n != nil {
+			return n
+		}
+	case *BlockStatement:
+		if n != nil {
+			return n
+		}
+	case *FunctionLiteral:
+		if n != nil {
+			return n
+		}
+	case *LetStatement:
+		if n != nil {
+			return n
+		}
+	case *CallExpression:
+		if n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func kindOf(node Node) string {
+	return fmt.Sprintf("%T", node)[len("*ast."):]
+}
+
+type jsonNode struct {
+	Kind string  `json:"kind"`
+	Pos  jsonPos `json:"pos"`
+
+	Operator string `json:"operator"`
+
+	// name is a string, except in LetStatement and SelectorExpression
+	// where it is an Identifier
+	Name  json.RawMessage `json:"name"`
+	Value json.RawMessage `json:"value"`
+	Path  string          `json:"path"`
+
+	Statements  []json.RawMessage `json:"statements"`
+	Parameters  []json.RawMessage `json:"parameters"`
+	Defaults    []json.RawMessage `json:"defaults"`
+	Arguments   []json.RawMessage `json:"arguments"`
+	Elements    []json.RawMessage `json:"elements"`
+	Parts       []json.RawMessage `json:"parts"`
+	Vars        []json.RawMessage `json:"vars"`
+	Methods     []json.RawMessage `json:"methods"`
+	Names       []json.RawMessage `json:"names"`
+	Alias       json.RawMessage   `json:"alias"`
+	Statement   json.RawMessage   `json:"statement"`
+	ReturnValue json.RawMessage   `json:"returnValue"`
+	Expression  json.RawMessage   `json:"expression"`
+	Right       json.RawMessage   `json:"right"`
+	Left        json.RawMessage   `json:"left"`
+	Condition   json.RawMessage   `json:"condition"`
+	Consequence json.RawMessage   `json:"consequence"`
+	ElseIfs     []json.RawMessage `json:"elseIfs"`
+	Alternative json.RawMessage   `json:"alternative"`
+	Block       json.RawMessage   `json:"block"`
+	Param       json.RawMessage   `json:"param"`
+	Catch       json.RawMessage   `json:"catch"`
+	Finally     json.RawMessage   `json:"finally"`
+	Call        json.RawMessage   `json:"call"`
+	Variable    json.RawMessage   `json:"variable"`
+	Cases       []json.RawMessage `json:"cases"`
+	Subject     json.RawMessage   `json:"subject"`
+	Arms        []json.RawMessage `json:"arms"`
+	Pattern     json.RawMessage   `json:"pattern"`
+	Guard       json.RawMessage   `json:"guard"`
+	Rest        json.RawMessage   `json:"rest"` // a bool in FunctionLiteral
+	Default     json.RawMessage   `json:"default"`
+	Iterable    json.RawMessage   `json:"iterable"`
+	Body        json.RawMessage   `json:"body"`
+	Function    json.RawMessage   `json:"function"`
+	Index       json.RawMessage   `json:"index"`
+	Start       json.RawMessage   `json:"start"`
+	End         json.RawMessage   `json:"end"`
+	Step        json.RawMessage   `json:"step"`
+	Optional    bool              `json:"optional"`
+	Const       bool              `json:"const"`
+	Pairs       []struct {
+		Key   json.RawMessage `json:"key"`
+		Value json.RawMessage `json:"value"`
+	} `json:"pairs"`
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func decode(raw json.RawMessage) (Node, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	var n jsonNode
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, err
+	}
+	tok := func(t token.TokenType, literal string) token.Token {
+		return token.Token{Type: t, Literal: literal, Line: n.Pos.Line, Column: n.Pos.Column}
+	}
+	// leaf is the token of a node that is a single token, spanning the node.
+	leaf := func(t token.TokenType, literal string) token.Token {
+		tok := tok(t, literal)
+		tok.EndLine, tok.EndColumn = n.Pos.EndLine, n.Pos.EndColumn
+		return tok
+	}
+	// closing is the closing bracket of a node, which ends where the node
+	// does, or no token if the node has no position.
+	closing := func(t token.TokenType, literal string) token.Token {
+		if n.Pos.EndLine == 0 {
+			return token.Token{}
+		}
+		return token.Token{Type: t, Literal: literal, Line: n.Pos.EndLine, Column: n.Pos.EndColumn - len(literal),
+			EndLine: n.Pos.EndLine, EndColumn: n.Pos.EndColumn}
+	}
+	var name string
+	if n.Kind != "LetStatement" && n.Kind != "SelectorExpression" && !isNull(n.Name) {
+		if err := json.Unmarshal(n.Name, &name); err != nil {
+			return nil, fmt.Errorf("ast: %s name: %v", n.Kind, err)
+		}
+	}
+
+	switch n.Kind {
+	case "Program":
+		stmts, err := decodeStatements(n.Statements)
+		if err != nil {
+			return nil, err
+		}
+		return &Program{Statements: stmts}, nil
+	case "LetStatement":
+		ident, err := decodeIdentifier(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		if fl, ok := value.(*FunctionLiteral); ok && fl.Name == "" {
+			fl.Name = ident.Value
+		}
+		if n.Const {
+			return &LetStatement{Token: tok(token.CONST, "const"), Name: ident, Value: value, Const: true}, nil
+		}
+		return &LetStatement{Token: tok(token.LET, "let"), Name: ident, Value: value}, nil
+	case "LetPatternStatement":
+		pattern, err := decodePattern(n.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &LetPatternStatement{Token: tok(token.LET, "let"), Pattern: pattern, Value: value}, nil
+	case "BlockStatement":
+		stmts, err := decodeStatements(n.Statements)
+		if err != nil {
+			return nil, err
+		}
+		return &BlockStatement{Token: tok(token.LBRACE, "{"), Statements: stmts, Rbrace: closing(token.RBRACE, "}")}, nil
+	case "ReturnStatement":
+		value, err := decodeExpression(n.ReturnValue)
+		if err != nil {
+			return nil, err
+		}
+		return &ReturnStatement{Token: tok(token.RETURN, "return"), ReturnValue: value}, nil
+	case "ExpressionStatement":
+		exp, err := decodeExpression(n.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return &ExpressionStatement{Token: StartToken(exp), Expression: exp}, nil
+	case "Identifier":
+		return &Identifier{Token: leaf(token.IDENT, name), Value: name}, nil
+	case "IntegerLiteral":
+		var v int64
+		if err := json.Unmarshal(n.Value, &v); err != nil {
+			return nil, fmt.Errorf("ast: IntegerLiteral value: %v", err)
+		}
+		return &IntegerLiteral{Token: leaf(token.INT, strconv.FormatInt(v, 10)), Value: v}, nil
+	case "StringLiteral":
+		var v string
+		if err := json.Unmarshal(n.Value, &v); err != nil {
+			return nil, fmt.Errorf("ast: StringLiteral value: %v", err)
+		}
+		return &StringLiteral{Token: leaf(token.STRING, v), Value: v}, nil
+	case "Boolean":
+		var v bool
+		if err := json.Unmarshal(n.Value, &v); err != nil {
+			return nil, fmt.Errorf("ast: Boolean value: %v", err)
+		}
+		if v {
+			return &Boolean{Token: leaf(token.TRUE, "true"), Value: v}, nil
+		}
+		return &Boolean{Token: leaf(token.FALSE, "false"), Value: v}, nil
+	case "PrefixExpression":
+		right, err := decodeExpression(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &PrefixExpression{
+			Token:    tok(token.TokenType(n.Operator), n.Operator),
+			Operator: n.Operator,
+			Right:    right,
+		}, nil
+	case "InfixExpression":
+		left, err := decodeExpression(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeExpression(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &InfixExpression{
+			Token:    tok(token.TokenType(n.Operator), n.Operator),
+			Left:     left,
+			Operator: n.Operator,
+			Right:    right,
+		}, nil
+	case "IfExpression":
+		cond, err := decodeExpression(n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		cons, err := decodeBlock(n.Consequence)
+		if err != nil {
+			return nil, err
+		}
+		exp := &IfExpression{Token: tok(token.IF, "if"), Condition: cond, Consequence: cons}
+		for _, raw := range n.ElseIfs {
+			node, err := decode(raw)
+			if err != nil {
+				return nil, err
+			}
+			ei, ok := node.(*ElseIf)
+			if !ok {
+				return nil, fmt.Errorf("ast: expected ElseIf, got %T", node)
+			}
+			exp.ElseIfs = append(exp.ElseIfs, ei)
+		}
+		if exp.Alternative, err = optionalBlock(n.Alternative); err != nil {
+			return nil, err
+		}
+		return exp, nil
+	case "ElseIf":
+		cond, err := decodeExpression(n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		cons, err := decodeBlock(n.Consequence)
+		if err != nil {
+			return nil, err
+		}
+		return &ElseIf{Token: tok(token.IF, "if"), Condition: cond, Consequence: cons}, nil
+	case "ConditionalExpression":
+		cond, err := decodeExpression(n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		cons, err := decodeExpression(n.Consequence)
+		if err != nil {
+			return nil, err
+		}
+		alt, err := decodeExpression(n.Alternative)
+		if err != nil {
+			return nil, err
+		}
+		return &ConditionalExpression{Token: tok(token.QUESTION, "?"), Condition: cond, Consequence: cons, Alternative: alt}, nil
+	case "TryExpression":
+		exp := &TryExpression{Token: tok(token.TRY, "try")}
+		var err error
+		if exp.Block, err = decodeBlock(n.Block); err != nil {
+			return nil, err
+		}
+		if !isNull(n.Param) {
+			if exp.Param, err = decodeIdentifier(n.Param); err != nil {
+				return nil, err
+			}
+		}
+		if exp.Catch, err = optionalBlock(n.Catch); err != nil {
+			return nil, err
+		}
+		if exp.Finally, err = optionalBlock(n.Finally); err != nil {
+			return nil, err
+		}
+		return exp, nil
+	case "ThrowStatement":
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &ThrowStatement{Token: tok(token.THROW, "throw"), Value: value}, nil
+	case "DeferStatement":
+		call, err := decodeCall(n.Call)
+		if err != nil {
+			return nil, err
+		}
+		return &DeferStatement{Token: tok(token.DEFER, "defer"), Call: call}, nil
+	case "SpawnExpression":
+		call, err := decodeCall(n.Call)
+		if err != nil {
+			return nil, err
+		}
+		return &SpawnExpression{Token: tok(token.SPAWN, "spawn"), Call: call}, nil
+	case "SelectExpression":
+		exp := &SelectExpression{Token: tok(token.SELECT, "select"), Rbrace: closing(token.RBRACE, "}")}
+		for _, raw := range n.Cases {
+			node, err := decode(raw)
+			if err != nil {
+				return nil, err
+			}
+			c, ok := node.(*SelectCase)
+			if !ok {
+				return nil, fmt.Errorf("ast: expected SelectCase, got %T", node)
+			}
+			exp.Cases = append(exp.Cases, c)
+		}
+		var err error
+		if exp.Default, err = optionalBlock(n.Default); err != nil {
+			return nil, err
+		}
+		return exp, nil
+	case "SelectCase":
+		c := &SelectCase{Token: tok(token.CASE, "case")}
+		var err error
+		if !isNull(n.Variable) {
+			if c.Variable, err = decodeIdentifier(n.Variable); err != nil {
+				return nil, err
+			}
+		}
+		if c.Call, err = decodeCall(n.Call); err != nil {
+			return nil, err
+		}
+		if c.Body, err = decodeBlock(n.Body); err != nil {
+			return nil, err
+		}
+		return c, nil
+	case "MatchExpression":
+		exp := &MatchExpression{Token: tok(token.MATCH, "match"), Rbrace: closing(token.RBRACE, "}")}
+		var err error
+		if exp.Subject, err = decodeExpression(n.Subject); err != nil {
+			return nil, err
+		}
+		for _, raw := range n.Arms {
+			node, err := decode(raw)
+			if err != nil {
+				return nil, err
+			}
+			arm, ok := node.(*MatchArm)
+			if !ok {
+				return nil, fmt.Errorf("ast: expected MatchArm, got %T", node)
+			}
+			exp.Arms = append(exp.Arms, arm)
+		}
+		return exp, nil
+	case "MatchArm":
+		arm := &MatchArm{}
+		var err error
+		if arm.Pattern, err = decodePattern(n.Pattern); err != nil {
+			return nil, err
+		}
+		if arm.Guard, err = optionalExpression(n.Guard); err != nil {
+			return nil, err
+		}
+		if arm.Body, err = decodeBlock(n.Body); err != nil {
+			return nil, err
+		}
+		return arm, nil
+	case "LiteralPattern":
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &LiteralPattern{Token: StartToken(value), Value: value}, nil
+	case "ArrayPattern":
+		pattern := &ArrayPattern{Token: tok(token.LBRACKET, "["), Rbracket: closing(token.RBRACKET, "]")}
+		for _, raw := range n.Elements {
+			element, err := decodePattern(raw)
+			if err != nil {
+				return nil, err
+			}
+			pattern.Elements = append(pattern.Elements, element)
+		}
+		if !isNull(n.Rest) {
+			rest, err := decodeIdentifier(n.Rest)
+			if err != nil {
+				return nil, err
+			}
+			pattern.Rest = rest
+		}
+		return pattern, nil
+	case "HashPattern":
+		pattern := &HashPattern{Token: tok(token.LBRACE, "{"), Rbrace: closing(token.RBRACE, "}")}
+		for _, p := range n.Pairs {
+			key, err := decodeExpression(p.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodePattern(p.Value)
+			if err != nil {
+				return nil, err
+			}
+			pattern.Keys = append(pattern.Keys, key)
+			pattern.Values = append(pattern.Values, value)
+		}
+		return pattern, nil
+	case "DefaultPattern":
+		pattern := &DefaultPattern{Token: tok(token.ASSIGN, "=")}
+		var err error
+		if pattern.Pattern, err = decodePattern(n.Pattern); err != nil {
+			return nil, err
+		}
+		if pattern.Default, err = decodeExpression(n.Default); err != nil {
+			return nil, err
+		}
+		return pattern, nil
+	case "YieldExpression":
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &YieldExpression{Token: tok(token.YIELD, "yield"), Value: value}, nil
+	case "ForExpression":
+		exp := &ForExpression{Token: tok(token.FOR, "for")}
+		var err error
+		if exp.Variable, err = decodeIdentifier(n.Variable); err != nil {
+			return nil, err
+		}
+		if exp.Iterable, err = decodeExpression(n.Iterable); err != nil {
+			return nil, err
+		}
+		if exp.Body, err = decodeBlock(n.Body); err != nil {
+			return nil, err
+		}
+		return exp, nil
+	case "FunctionLiteral":
+		return decodeFunction(n, name, tok(token.FUNCTION, "fn"))
+	case "MacroLiteral":
+		fn, err := decodeFunction(n, "", tok(token.MACRO, "macro"))
+		if err != nil {
+			return nil, err
+		}
+		fl := fn.(*FunctionLiteral)
+		return &MacroLiteral{Token: fl.Token, Parameters: fl.Parameters, Body: fl.Body}, nil
+	case "CallExpression":
+		fn, err := decodeExpression(n.Function)
+		if err != nil {
+			return nil, err
+		}
+		args, err := decodeExpressions(n.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		return &CallExpression{Token: tok(token.LPAREN, "("), Function: fn, Arguments: args, Rparen: closing(token.RPAREN, ")")}, nil
+	case "SpreadExpression":
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &SpreadExpression{Token: tok(token.ELLIPSIS, "..."), Value: value}, nil
+	case "KeywordArgument":
+		value, err := decodeExpression(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &KeywordArgument{Token: tok(token.IDENT, name), Name: name, Value: value}, nil
+	case "ArrayLiteral":
+		elements, err := decodeExpressions(n.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayLiteral{Token: tok(token.LBRACKET, "["), Elements: elements, Rbracket: closing(token.RBRACKET, "]")}, nil
+	case "InterpolatedString":
+		parts, err := decodeExpressions(n.Parts)
+		if err != nil {
+			return nil, err
+		}
+		str := &InterpolatedString{Parts: parts}
+		str.Token = leaf(token.TEMPLATE, str.String())
+		return str, nil
+	case "IndexExpression":
+		left, err := decodeExpression(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		index, err := decodeExpression(n.Index)
+		if err != nil {
+			return nil, err
+		}
+		if n.Optional {
+			return &IndexExpression{Token: tok(token.QLBRACKET, "?["), Left: left, Index: index, Optional: true, Rbracket: closing(token.RBRACKET, "]")}, nil
+		}
+		return &IndexExpression{Token: tok(token.LBRACKET, "["), Left: left, Index: index, Rbracket: closing(token.RBRACKET, "]")}, nil
+	case "SliceExpression":
+		left, err := decodeExpression(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		start, err := optionalExpression(n.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := optionalExpression(n.End)
+		if err != nil {
+			return nil, err
+		}
+		step, err := optionalExpression(n.Step)
+		if err != nil {
+			return nil, err
+		}
+		return &SliceExpression{Token: tok(token.LBRACKET, "["), Left: left, Start: start, End: end, Step: step,
+			Rbracket: closing(token.RBRACKET, "]")}, nil
+	case "HashLiteral":
+		hash := &HashLiteral{Token: tok(token.LBRACE, "{"), Paris: make(map[Expression]Expression), Rbrace: closing(token.RBRACE, "}")}
+		for _, p := range n.Pairs {
+			key, err := decodeExpression(p.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeExpression(p.Value)
+			if err != nil {
+				return nil, err
+			}
+			hash.Paris[key] = value
+			hash.Keys = append(hash.Keys, key)
+		}
+		return hash, nil
+	case "StructDeclarion":
+		vars := []*Identifier{}
+		for _, raw := range n.Vars {
+			v, err := decodeIdentifier(raw)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, v)
+		}
+		methods := []*FunctionLiteral{}
+		for _, raw := range n.Methods {
+			m, err := decode(raw)
+			if err != nil {
+				return nil, err
+			}
+			fn, ok := m.(*FunctionLiteral)
+			if !ok {
+				return nil, fmt.Errorf("ast: expected FunctionLiteral, got %T", m)
+			}
+			methods = append(methods, fn)
+		}
+		return &StructDeclarion{Token: tok(token.STRUCT, "struct"), Name: name, Vars: vars, Methods: methods,
+			Rbrace: closing(token.RBRACE, "}")}, nil
+	case "FunctionDeclarionStatement":
+		body, err := decode(n.Body)
+		if err != nil {
+			return nil, err
+		}
+		fn, ok := body.(*FunctionLiteral)
+		if !ok {
+			return nil, fmt.Errorf("ast: expected FunctionLiteral, got %T", body)
+		}
+		return &FunctionDeclarionStatement{Token: tok(token.FUNCTION, "fn"), Name: name, Body: fn}, nil
+	case "ImportStatement":
+		stmt := &ImportStatement{Token: tok(token.IMPORT, "import"), Path: n.Path, PathToken: closing(token.STRING, strconv.Quote(n.Path))}
+		if !isNull(n.Alias) {
+			alias, err := decodeIdentifier(n.Alias)
+			if err != nil {
+				return nil, err
+			}
+			stmt.Alias = alias
+		}
+		for _, raw := range n.Names {
+			ident, err := decodeIdentifier(raw)
+			if err != nil {
+				return nil, err
+			}
+			stmt.Names = append(stmt.Names, ident)
+		}
+		return stmt, nil
+	case "ExportStatement":
+		node, err := decode(n.Statement)
+		if err != nil {
+			return nil, err
+		}
+		let, ok := node.(*LetStatement)
+		if !ok {
+			return nil, fmt.Errorf("ast: expected LetStatement, got %T", node)
+		}
+		return &ExportStatement{Token: tok(token.EXPORT, "export"), Statement: let}, nil
+	case "SelectorExpression":
+		left, err := decodeExpression(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		ident, err := decodeIdentifier(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		if n.Optional {
+			return &SelectorExpression{Token: tok(token.QDOT, "?."), Left: left, Name: ident, Optional: true}, nil
+		}
+		return &SelectorExpression{Token: tok(token.DOT, "."), Left: left, Name: ident}, nil
+	}
+	return nil, fmt.Errorf("ast: unknown node kind %q", n.Kind)
+}
+
+func decodeFunction(n jsonNode, name string, tok token.Token) (Node, error) {
+	params := []*Identifier{}
+	for _, raw := range n.Parameters {
+		p, err := decodeIdentifier(raw)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+	}
+	body, err := optionalBlock(n.Body)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		body = &BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}}
+	}
+	fn := &FunctionLiteral{Token: tok, Name: name, Parameters: params, Body: body}
+	if n.Defaults != nil {
+		// parameters without a default have a null one
+		fn.Defaults = make([]Expression, 0, len(n.Defaults))
+		for _, raw := range n.Defaults {
+			dflt, err := optionalExpression(raw)
+			if err != nil {
+				return nil, err
+			}
+			fn.Defaults = append(fn.Defaults, dflt)
+		}
+	}
+	if !isNull(n.Rest) {
+		if err := json.Unmarshal(n.Rest, &fn.Rest); err != nil {
+			return nil, fmt.Errorf("ast: FunctionLiteral rest: %v", err)
+		}
+	}
+	if err := checkParameters(fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// checkParameters checks that the parameters of fn are such as the parser
+// gives: distinct, those after one with a default having one too, and the
+// rest parameter, which has none, last.
+func checkParameters(fn *FunctionLiteral) error {
+	seen := map[string]bool{}
+	for _, p := range fn.Parameters {
+		if seen[p.Value] {
+			return fmt.Errorf("ast: FunctionLiteral duplicate parameter %s", p.Value)
+		}
+		seen[p.Value] = true
+	}
+	positional := len(fn.Parameters)
+	if fn.Rest {
+		if positional == 0 {
+			return fmt.Errorf("ast: FunctionLiteral rest without parameters")
+		}
+		positional--
+	}
+	if len(fn.Defaults) > positional {
+		return fmt.Errorf("ast: FunctionLiteral has %d defaults for %d parameters", len(fn.Defaults), positional)
+	}
+	defaulted := false
+	for i, p := range fn.Parameters[:positional] {
+		if fn.Default(i) != nil {
+			defaulted = true
+		} else if defaulted {
+			return fmt.Errorf("ast: FunctionLiteral parameter %s must have a default, following one that has", p.Value)
+		}
+	}
+	return nil
+}
+func decodeStatements(list []json.RawMessage) ([]Statement, error) {
+	stmts := make([]Statement, 0, len(list))
+	for _, raw := range list {
+		node, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		stmt, ok := node.(Statement)
+		if !ok {
+			return nil, fmt.Errorf("ast: expected statement, got %T", node)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// decodeExpression, decodePattern, decodeCall and decodeBlock reject a
+// missing node, so that a decoded tree has every child the parser would
+// give it; optionalExpression and optionalBlock are for the slots that may
+// be empty.
+func decodeExpression(raw json.RawMessage) (Expression, error) {
+	node, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	exp, ok := node.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected expression, got %T", node)
+	}
+	return exp, nil
+}
+func decodeExpressions(list []json.RawMessage) ([]Expression, error) {
+	exps := make([]Expression, 0, len(list))
+	for _, raw := range list {
+		exp, err := decodeExpression(raw)
+		if err != nil {
+			return nil, err
+		}
+		exps = append(exps, exp)
+	}
+	return exps, nil
+}
+func optionalExpression(raw json.RawMessage) (Expression, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	return decodeExpression(raw)
+}
+func decodePattern(raw json.RawMessage) (Pattern, error) {
+	node, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	pattern, ok := node.(Pattern)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected pattern, got %T", node)
+	}
+	return pattern, nil
+}
+func decodeIdentifier(raw json.RawMessage) (*Identifier, error) {
+	node, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected Identifier, got %T", node)
+	}
+	return ident, nil
+}
+func decodeCall(raw json.RawMessage) (*CallExpression, error) {
+	node, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := node.(*CallExpression)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected CallExpression, got %T", node)
+	}
+	return call, nil
+}
+func decodeBlock(raw json.RawMessage) (*BlockStatement, error) {
+	node, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected BlockStatement, got %T", node)
+	}
+	return block, nil
+}
+func optionalBlock(raw json.RawMessage) (*BlockStatement, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	return decodeBlock(raw)
+}
@@ -0,0 +1,619 @@
+package ast
+
+import "fmt"
+
+// An ApplyFunc is invoked by Rewrite for each non-nil node n, before
+// and/or after the node's children, using a Cursor describing
+// the current node and providing operations on it.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Rewrite for details.
+type ApplyFunc func(*Cursor) bool
+
+// A Cursor describes a node encountered during Rewrite.
+// Information about the node and its parent is available
+// from the Node, Parent, Name, and Index methods.
+type Cursor struct {
+	parent  Node
+	name    string
+	index   int
+	node    Node
+	deleted bool
+}
+
+// Node returns the current Node, including any replacement made by Replace.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current Node as it was before rewriting.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent Node field that contains the current
+// Node, e.g. "Left" or "Statements".
+func (c *Cursor) Name() string { return c.name }
+
+// Index reports the index of the current Node in the slice of Nodes that
+// contains it, or a value < 0 if the current Node is not part of a slice.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current Node with n. The replacement node is not
+// walked by Rewrite.
+func (c *Cursor) Replace(n Node) { c.node = n }
+
+// Delete deletes the current Node from its containing statement list.
+// Replacing a statement with nil has the same effect.
+// It panics if the current node is not part of a statement list.
+func (c *Cursor) Delete() {
+	if c.index < 0 || (c.name != "Statements") {
+		panic("ast.Rewrite: Delete node not contained in a statement list")
+	}
+	c.deleted = true
+}
+
+// Rewrite traverses a syntax tree recursively, starting with root,
+// and calling pre and post for each node as described below.
+// Rewrite returns the syntax tree, possibly modified.
+//
+// The tree is copy-on-write: nodes are never modified in place. A node
+// whose children changed is replaced by a shallow copy, so every node on
+// the path from root to a change is new and everything else is shared
+// with the original tree.
+//
+// If pre is not nil, it is called for each node before the node's
+// children are traversed (pre-order). If pre returns false, no
+// children are traversed, and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false,
+// post is called for each node after its children are traversed
+// (post-order). If post returns false, traversal is terminated; the
+// changes made so far are kept in the returned tree.
+//
+// Children are traversed in the same order as Walk.
+func Rewrite(root Node, pre, post ApplyFunc) Node {
+	r := &rewriter{pre: pre, post: post}
+	result, _ := r.apply(nil, "", -1, root)
+	return result
+}
+
+type rewriter struct {
+	pre, post ApplyFunc
+	aborted   bool
+}
+
+func (r *rewriter) apply(parent Node, name string, index int, n Node) (Node, bool) {
+	if r.aborted {
+		return n, false
+	}
+	c := &Cursor{parent: parent, name: name, index: index, node: n}
+	if r.pre != nil && !r.pre(c) {
+		return c.node, c.deleted
+	}
+	if c.deleted {
+		return nil, true
+	}
+	if c.node == n && n != nil {
+		c.node = r.children(n)
+	}
+	if r.post != nil && !r.post(c) {
+		r.aborted = true
+	}
+	return c.node, c.deleted
+}
+
+func (r *rewriter) children(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		if stmts, ok := r.statements(n, n.Statements); ok {
+			cp := *n
+			cp.Statements = stmts
+			return &cp
+		}
+	case *BlockStatement:
+		if stmts, ok := r.statements(n, n.Statements); ok {
+			cp := *n
+			cp.Statements = stmts
+			return &cp
+		}
+	case *LetStatement:
+		name := r.identifier(n, "Name", -1, n.Name)
+		value := r.expression(n, "Value", -1, n.Value)
+		if name != n.Name || value != n.Value {
+			cp := *n
+			cp.Name, cp.Value = name, value
+			return &cp
+		}
+	case *LetPatternStatement:
+		pattern := r.pattern(n, "Pattern", -1, n.Pattern)
+		value := r.expression(n, "Value", -1, n.Value)
+		if pattern != n.Pattern || value != n.Value {
+			cp := *n
+			cp.Pattern, cp.Value = pattern, value
+			return &cp
+		}
+	case *ReturnStatement:
+		if value := r.expression(n, "ReturnValue", -1, n.ReturnValue); value != n.ReturnValue {
+			cp := *n
+			cp.ReturnValue = value
+			return &cp
+		}
+	case *ExpressionStatement:
+		if exp := r.expression(n, "Expression", -1, n.Expression); exp != n.Expression {
+			cp := *n
+			cp.Expression = exp
+			return &cp
+		}
+	case *PrefixExpression:
+		if right := r.expression(n, "Right", -1, n.Right); right != n.Right {
+			cp := *n
+			cp.Right = right
+			return &cp
+		}
+	case *InfixExpression:
+		left := r.expression(n, "Left", -1, n.Left)
+		right := r.expression(n, "Right", -1, n.Right)
+		if left != n.Left || right != n.Right {
+			cp := *n
+			cp.Left, cp.Right = left, right
+			return &cp
+		}
+	case *IfExpression:
+		cond := r.expression(n, "Condition", -1, n.Condition)
+		cons := r.block(n, "Consequence", n.Consequence)
+		elseIfs, changed := r.elseIfs(n, "ElseIfs", n.ElseIfs)
+		alt := r.block(n, "Alternative", n.Alternative)
+		if changed || cond != n.Condition || cons != n.Consequence || alt != n.Alternative {
+			cp := *n
+			cp.Condition, cp.Consequence, cp.ElseIfs, cp.Alternative = cond, cons, elseIfs, alt
+			return &cp
+		}
+	case *ElseIf:
+		cond := r.expression(n, "Condition", -1, n.Condition)
+		cons := r.block(n, "Consequence", n.Consequence)
+		if cond != n.Condition || cons != n.Consequence {
+			cp := *n
+			cp.Condition, cp.Consequence = cond, cons
+			return &cp
+		}
+	case *ConditionalExpression:
+		cond := r.expression(n, "Condition", -1, n.Condition)
+		cons := r.expression(n, "Consequence", -1, n.Consequence)
+		alt := r.expression(n, "Alternative", -1, n.Alternative)
+		if cond != n.Condition || cons != n.Consequence || alt != n.Alternative {
+			cp := *n
+			cp.Condition, cp.Consequence, cp.Alternative = cond, cons, alt
+			return &cp
+		}
+	case *TryExpression:
+		block := r.block(n, "Block", n.Block)
+		param := r.identifier(n, "Param", -1, n.Param)
+		catch := r.block(n, "Catch", n.Catch)
+		finally := r.block(n, "Finally", n.Finally)
+		if block != n.Block || param != n.Param || catch != n.Catch || finally != n.Finally {
+			cp := *n
+			cp.Block, cp.Param, cp.Catch, cp.Finally = block, param, catch, finally
+			return &cp
+		}
+	case *ThrowStatement:
+		if value := r.expression(n, "Value", -1, n.Value); value != n.Value {
+			cp := *n
+			cp.Value = value
+			return &cp
+		}
+	case *DeferStatement:
+		if call := r.call(n, "Call", n.Call); call != n.Call {
+			cp := *n
+			cp.Call = call
+			return &cp
+		}
+	case *SpawnExpression:
+		if call := r.call(n, "Call", n.Call); call != n.Call {
+			cp := *n
+			cp.Call = call
+			return &cp
+		}
+	case *SelectExpression:
+		cases, changed := r.selectCases(n, "Cases", n.Cases)
+		def := r.block(n, "Default", n.Default)
+		if changed || def != n.Default {
+			cp := *n
+			cp.Cases, cp.Default = cases, def
+			return &cp
+		}
+	case *SelectCase:
+		variable := r.identifier(n, "Variable", -1, n.Variable)
+		call := r.call(n, "Call", n.Call)
+		body := r.block(n, "Body", n.Body)
+		if variable != n.Variable || call != n.Call || body != n.Body {
+			cp := *n
+			cp.Variable, cp.Call, cp.Body = variable, call, body
+			return &cp
+		}
+	case *MatchExpression:
+		subject := r.expression(n, "Subject", -1, n.Subject)
+		arms, changed := r.matchArms(n, "Arms", n.Arms)
+		if changed || subject != n.Subject {
+			cp := *n
+			cp.Subject, cp.Arms = subject, arms
+			return &cp
+		}
+	case *MatchArm:
+		pattern := r.pattern(n, "Pattern", -1, n.Pattern)
+		guard := r.expression(n, "Guard", -1, n.Guard)
+		body := r.block(n, "Body", n.Body)
+		if pattern != n.Pattern || guard != n.Guard || body != n.Body {
+			cp := *n
+			cp.Pattern, cp.Guard, cp.Body = pattern, guard, body
+			return &cp
+		}
+	case *LiteralPattern:
+		if value := r.expression(n, "Value", -1, n.Value); value != n.Value {
+			cp := *n
+			cp.Value = value
+			return &cp
+		}
+	case *ArrayPattern:
+		elements, changed := r.patterns(n, "Elements", n.Elements)
+		rest := r.identifier(n, "Rest", -1, n.Rest)
+		if changed || rest != n.Rest {
+			cp := *n
+			cp.Elements, cp.Rest = elements, rest
+			return &cp
+		}
+	case *HashPattern:
+		changed := false
+		keys := make([]Expression, len(n.Keys))
+		values := make([]Pattern, len(n.Values))
+		for i, k := range n.Keys {
+			keys[i] = r.expression(n, "Keys", i, k)
+			values[i] = r.pattern(n, "Values", i, n.Values[i])
+			changed = changed || keys[i] != k || values[i] != n.Values[i]
+		}
+		if changed {
+			cp := *n
+			cp.Keys, cp.Values = keys, values
+			return &cp
+		}
+	case *DefaultPattern:
+		pattern := r.pattern(n, "Pattern", -1, n.Pattern)
+		dflt := r.expression(n, "Default", -1, n.Default)
+		if pattern != n.Pattern || dflt != n.Default {
+			cp := *n
+			cp.Pattern, cp.Default = pattern, dflt
+			return &cp
+		}
+	case *YieldExpression:
+		if value := r.expression(n, "Value", -1, n.Value); value != n.Value {
+			cp := *n
+			cp.Value = value
+			return &cp
+		}
+	case *ForExpression:
+		variable := r.identifier(n, "Variable", -1, n.Variable)
+		iterable := r.expression(n, "Iterable", -1, n.Iterable)
+		body := r.block(n, "Body", n.Body)
+		if variable != n.Variable || iterable != n.Iterable || body != n.Body {
+			cp := *n
+			cp.Variable, cp.Iterable, cp.Body = variable, iterable, body
+			return &cp
+		}
+	case *FunctionLiteral:
+		params, changed := r.identifiers(n, "Parameters", n.Parameters)
+		defaults, defaultsChanged := r.expressions(n, "Defaults", n.Defaults)
+		body := r.block(n, "Body", n.Body)
+		if changed || defaultsChanged || body != n.Body {
+			cp := *n
+			cp.Parameters, cp.Body = params, body
+			if defaultsChanged {
+				cp.Defaults = defaults
+			}
+			return &cp
+		}
+	case *MacroLiteral:
+		params, changed := r.identifiers(n, "Parameters", n.Parameters)
+		body := r.block(n, "Body", n.Body)
+		if changed || body != n.Body {
+			cp := *n
+			cp.Parameters, cp.Body = params, body
+			return &cp
+		}
+	case *CallExpression:
+		fn := r.expression(n, "Function", -1, n.Function)
+		args, changed := r.expressions(n, "Arguments", n.Arguments)
+		if changed || fn != n.Function {
+			cp := *n
+			cp.Function, cp.Arguments = fn, args
+			return &cp
+		}
+	case *SpreadExpression:
+		if value := r.expression(n, "Value", -1, n.Value); value != n.Value {
+			cp := *n
+			cp.Value = value
+			return &cp
+		}
+	case *KeywordArgument:
+		if value := r.expression(n, "Value", -1, n.Value); value != n.Value {
+			cp := *n
+			cp.Value = value
+			return &cp
+		}
+	case *ArrayLiteral:
+		if elements, changed := r.expressions(n, "Elements", n.Elements); changed {
+			cp := *n
+			cp.Elements = elements
+			return &cp
+		}
+	case *InterpolatedString:
+		if parts, changed := r.expressions(n, "Parts", n.Parts); changed {
+			cp := *n
+			cp.Parts = parts
+			return &cp
+		}
+	case *IndexExpression:
+		left := r.expression(n, "Left", -1, n.Left)
+		index := r.expression(n, "Index", -1, n.Index)
+		if left != n.Left || index != n.Index {
+			cp := *n
+			cp.Left, cp.Index = left, index
+			return &cp
+		}
+	case *SliceExpression:
+		left := r.expression(n, "Left", -1, n.Left)
+		start := r.expression(n, "Start", -1, n.Start)
+		end := r.expression(n, "End", -1, n.End)
+		step := r.expression(n, "Step", -1, n.Step)
+		if left != n.Left || start != n.Start || end != n.End || step != n.Step {
+			cp := *n
+			cp.Left, cp.Start, cp.End, cp.Step = left, start, end, step
+			return &cp
+		}
+	case *HashLiteral:
+		changed := false
+		keys := make([]Expression, len(n.Keys))
+		values := make([]Expression, len(n.Keys))
+		for i, k := range n.Keys {
+			keys[i] = r.expression(n, "Keys", i, k)
+			values[i] = r.expression(n, "Paris", i, n.Paris[k])
+			changed = changed || keys[i] != k || values[i] != n.Paris[k]
+		}
+		if changed {
+			cp := *n
+			cp.Keys = keys
+			cp.Paris = make(map[Expression]Expression)
+			for i, k := range keys {
+				cp.Paris[k] = values[i]
+			}
+			return &cp
+		}
+	case *StructDeclarion:
+		vars, varsChanged := r.identifiers(n, "Vars", n.Vars)
+		methods, methodsChanged := r.functions(n, "Methods", n.Methods)
+		if varsChanged || methodsChanged {
+			cp := *n
+			cp.Vars, cp.Methods = vars, methods
+			return &cp
+		}
+	case *FunctionDeclarionStatement:
+		if body := r.function(n, "Body", -1, n.Body); body != n.Body {
+			cp := *n
+			cp.Body = body
+			return &cp
+		}
+	case *ImportStatement:
+		alias := r.identifier(n, "Alias", -1, n.Alias)
+		names, changed := r.identifiers(n, "Names", n.Names)
+		if changed || alias != n.Alias {
+			cp := *n
+			cp.Alias, cp.Names = alias, names
+			return &cp
+		}
+	case *ExportStatement:
+		if let := r.let(n, "Statement", n.Statement); let != n.Statement {
+			cp := *n
+			cp.Statement = let
+			return &cp
+		}
+	case *SelectorExpression:
+		left := r.expression(n, "Left", -1, n.Left)
+		name := r.identifier(n, "Name", -1, n.Name)
+		if left != n.Left || name != n.Name {
+			cp := *n
+			cp.Left, cp.Name = left, name
+			return &cp
+		}
+	}
+	return node
+}
+
+func (r *rewriter) statements(parent Node, list []Statement) ([]Statement, bool) {
+	changed := false
+	result := make([]Statement, 0, len(list))
+	for i, s := range list {
+		n, deleted := r.apply(parent, "Statements", i, s)
+		if deleted || n == nil {
+			changed = true
+			continue
+		}
+		stmt, ok := n.(Statement)
+		if !ok {
+			panic(replaceError(parent, "Statements", n))
+		}
+		changed = changed || stmt != s
+		result = append(result, stmt)
+	}
+	return result, changed
+}
+func (r *rewriter) expression(parent Node, name string, index int, e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, index, e)
+	if n == nil {
+		return nil
+	}
+	exp, ok := n.(Expression)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return exp
+}
+func (r *rewriter) expressions(parent Node, name string, list []Expression) ([]Expression, bool) {
+	changed := false
+	result := make([]Expression, len(list))
+	for i, e := range list {
+		result[i] = r.expression(parent, name, i, e)
+		changed = changed || result[i] != e
+	}
+	return result, changed
+}
+func (r *rewriter) identifier(parent Node, name string, index int, id *Identifier) *Identifier {
+	if id == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, index, id)
+	if n == nil {
+		return nil
+	}
+	ident, ok := n.(*Identifier)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return ident
+}
+func (r *rewriter) identifiers(parent Node, name string, list []*Identifier) ([]*Identifier, bool) {
+	changed := false
+	result := make([]*Identifier, len(list))
+	for i, id := range list {
+		result[i] = r.identifier(parent, name, i, id)
+		changed = changed || result[i] != id
+	}
+	return result, changed
+}
+func (r *rewriter) let(parent Node, name string, ls *LetStatement) *LetStatement {
+	if ls == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, -1, ls)
+	if n == nil {
+		return nil
+	}
+	let, ok := n.(*LetStatement)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return let
+}
+func (r *rewriter) block(parent Node, name string, b *BlockStatement) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, -1, b)
+	if n == nil {
+		return nil
+	}
+	block, ok := n.(*BlockStatement)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return block
+}
+func (r *rewriter) call(parent Node, name string, ce *CallExpression) *CallExpression {
+	if ce == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, -1, ce)
+	if n == nil {
+		return nil
+	}
+	call, ok := n.(*CallExpression)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return call
+}
+func (r *rewriter) function(parent Node, name string, index int, fl *FunctionLiteral) *FunctionLiteral {
+	if fl == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, index, fl)
+	if n == nil {
+		return nil
+	}
+	fn, ok := n.(*FunctionLiteral)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return fn
+}
+func (r *rewriter) functions(parent Node, name string, list []*FunctionLiteral) ([]*FunctionLiteral, bool) {
+	changed := false
+	result := make([]*FunctionLiteral, len(list))
+	for i, fl := range list {
+		result[i] = r.function(parent, name, i, fl)
+		changed = changed || result[i] != fl
+	}
+	return result, changed
+}
+func (r *rewriter) selectCases(parent Node, name string, list []*SelectCase) ([]*SelectCase, bool) {
+	changed := false
+	result := make([]*SelectCase, len(list))
+	for i, sc := range list {
+		n, _ := r.apply(parent, name, i, sc)
+		c, ok := n.(*SelectCase)
+		if !ok {
+			panic(replaceError(parent, name, n))
+		}
+		result[i] = c
+		changed = changed || c != sc
+	}
+	return result, changed
+}
+func (r *rewriter) elseIfs(parent Node, name string, list []*ElseIf) ([]*ElseIf, bool) {
+	changed := false
+	result := make([]*ElseIf, len(list))
+	for i, ei := range list {
+		n, _ := r.apply(parent, name, i, ei)
+		e, ok := n.(*ElseIf)
+		if !ok {
+			panic(replaceError(parent, name, n))
+		}
+		result[i] = e
+		changed = changed || e != ei
+	}
+	return result, changed
+}
+func (r *rewriter) matchArms(parent Node, name string, list []*MatchArm) ([]*MatchArm, bool) {
+	changed := false
+	result := make([]*MatchArm, len(list))
+	for i, ma := range list {
+		n, _ := r.apply(parent, name, i, ma)
+		arm, ok := n.(*MatchArm)
+		if !ok {
+			panic(replaceError(parent, name, n))
+		}
+		result[i] = arm
+		changed = changed || arm != ma
+	}
+	return result, changed
+}
+func (r *rewriter) pattern(parent Node, name string, index int, p Pattern) Pattern {
+	if p == nil {
+		return nil
+	}
+	n, _ := r.apply(parent, name, index, p)
+	pattern, ok := n.(Pattern)
+	if !ok {
+		panic(replaceError(parent, name, n))
+	}
+	return pattern
+}
+func (r *rewriter) patterns(parent Node, name string, list []Pattern) ([]Pattern, bool) {
+	changed := false
+	result := make([]Pattern, len(list))
+	for i, p := range list {
+		result[i] = r.pattern(parent, name, i, p)
+		changed = changed || result[i] != p
+	}
+	return result, changed
+}
+func replaceError(parent Node, name string, n Node) string {
+	return fmt.Sprintf("ast.Rewrite: cannot put %T in %T.%s", n, parent, name)
+}
@@ -0,0 +1,253 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkExpression(v, n.Value)
+	case *LetPatternStatement:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpression(v, n.Value)
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		for _, ei := range n.ElseIfs {
+			Walk(v, ei)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *ElseIf:
+		walkExpression(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+	case *ConditionalExpression:
+		walkExpression(v, n.Condition)
+		walkExpression(v, n.Consequence)
+		walkExpression(v, n.Alternative)
+	case *TryExpression:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+		if n.Param != nil {
+			Walk(v, n.Param)
+		}
+		if n.Catch != nil {
+			Walk(v, n.Catch)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+	case *ThrowStatement:
+		walkExpression(v, n.Value)
+	case *DeferStatement:
+		if n.Call != nil {
+			Walk(v, n.Call)
+		}
+	case *SpawnExpression:
+		if n.Call != nil {
+			Walk(v, n.Call)
+		}
+	case *SelectExpression:
+		for _, c := range n.Cases {
+			Walk(v, c)
+		}
+		if n.Default != nil {
+			Walk(v, n.Default)
+		}
+	case *SelectCase:
+		if n.Variable != nil {
+			Walk(v, n.Variable)
+		}
+		if n.Call != nil {
+			Walk(v, n.Call)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *MatchExpression:
+		walkExpression(v, n.Subject)
+		for _, a := range n.Arms {
+			Walk(v, a)
+		}
+	case *MatchArm:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpression(v, n.Guard)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *LiteralPattern:
+		walkExpression(v, n.Value)
+	case *ArrayPattern:
+		for _, e := range n.Elements {
+			Walk(v, e)
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+	case *HashPattern:
+		for i, k := range n.Keys {
+			walkExpression(v, k)
+			Walk(v, n.Values[i])
+		}
+	case *DefaultPattern:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpression(v, n.Default)
+	case *YieldExpression:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ForExpression:
+		if n.Variable != nil {
+			Walk(v, n.Variable)
+		}
+		if n.Iterable != nil {
+			Walk(v, n.Iterable)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			Walk(v, p)
+			walkExpression(v, n.Default(i))
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *MacroLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+	case *SpreadExpression:
+		walkExpression(v, n.Value)
+	case *KeywordArgument:
+		walkExpression(v, n.Value)
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+	case *InterpolatedString:
+		walkExpressions(v, n.Parts)
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+	case *SliceExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Start)
+		walkExpression(v, n.End)
+		walkExpression(v, n.Step)
+	case *HashLiteral:
+		for _, k := range n.Keys {
+			walkExpression(v, k)
+			walkExpression(v, n.Paris[k])
+		}
+	case *StructDeclarion:
+		for _, vr := range n.Vars {
+			Walk(v, vr)
+		}
+		for _, m := range n.Methods {
+			Walk(v, m)
+		}
+	case *FunctionDeclarionStatement:
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *ImportStatement:
+		if n.Alias != nil {
+			Walk(v, n.Alias)
+		}
+		for _, name := range n.Names {
+			Walk(v, name)
+		}
+	case *ExportStatement:
+		if n.Statement != nil {
+			Walk(v, n.Statement)
+		}
+	case *SelectorExpression:
+		walkExpression(v, n.Left)
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+	}
+
+	v.Visit(nil)
+}
+func walkStatements(v Visitor, list []Statement) {
+	for _, s := range list {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+func walkExpression(v Visitor, e Expression) {
+	if e != nil {
+		Walk(v, e)
+	}
+}
+func walkExpressions(v Visitor, list []Expression) {
+	for _, e := range list {
+		walkExpression(v, e)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
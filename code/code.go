@@ -21,6 +21,7 @@ const (
 	OpArray
 	OpHash
 	OpIndex
+	OpSlice
 	OpGetBuiltin
 	OpClosure
 	OpCurrentClosure
@@ -29,6 +30,13 @@ const (
 	OpSub
 	OpMul
 	OpDiv
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
 	OpPop
 
 	OpTrue
@@ -38,16 +46,43 @@ const (
 	OpNEqual
 	OpGT
 	OpLT
+	OpGE
+	OpLE
 
 	OpMinus
 	OpBang
+	OpBitNot
+	OpStringify
 
 	OpJumpIfNotTrue
 	OpJump
+	OpJumpIfFalseOrPop
+	OpJumpIfTrueOrPop
+	OpJumpIfNotNullOrPop
+	OpJumpIfNull
 	OpCall
 	OpReturn
 	OpReturnValue
 
+	OpTry
+	OpEndTry
+	OpEndFinally
+	OpThrow
+	OpDefer
+	OpYield
+	OpIter
+	OpIterNext
+	OpSpawn
+	OpSelect
+	OpMatchLiteral
+	OpMatchArray
+	OpMatchHash
+	OpNoMatch
+	OpMismatch
+	OpDefault
+	OpSpread
+	OpKeyword
+
 	OpGetGlobal
 	OpSetGlobal
 	OpGetLocal
@@ -61,6 +96,7 @@ var definitions = map[Opcode]*Definition{
 	OpArray:          {"OpArray", []int{2}},
 	OpHash:           {"OpHash", []int{2}},
 	OpIndex:          {"OpIndex", []int{}},
+	OpSlice:          {"OpSlice", []int{}},
 	OpGetBuiltin:     {"OpGetBuiltin", []int{1}},
 	OpClosure:        {"OpClosure", []int{2, 1}},
 	OpCurrentClosure: {"OpCurrentClosure", []int{}},
@@ -69,6 +105,15 @@ var definitions = map[Opcode]*Definition{
 	OpSub: {"OpSub", []int{}},
 	OpMul: {"OpMul", []int{}},
 	OpDiv: {"OpDiv", []int{}},
+	OpMod: {"OpMod", []int{}},
+	OpPow: {"OpPow", []int{}},
+
+	OpBitAnd: {"OpBitAnd", []int{}},
+	OpBitOr:  {"OpBitOr", []int{}},
+	OpBitXor: {"OpBitXor", []int{}},
+	OpShl:    {"OpShl", []int{}},
+	OpShr:    {"OpShr", []int{}},
+
 	OpPop: {"OpPop", []int{}},
 
 	OpTrue:  {"OpTrue", []int{}},
@@ -78,16 +123,85 @@ var definitions = map[Opcode]*Definition{
 	OpNEqual: {"OpNEqual", []int{}},
 	OpGT:     {"OpGT", []int{}},
 	OpLT:     {"OpLT", []int{}},
+	OpGE:     {"OpGE", []int{}},
+	OpLE:     {"OpLE", []int{}},
 
 	OpMinus: {"OpMinus", []int{}},
 	OpBang:  {"OpBang", []int{}},
 
+	OpBitNot: {"OpBitNot", []int{}},
+
+	OpStringify: {"OpStringify", []int{}},
+
 	OpJumpIfNotTrue: {"OpJumpIfNotTrue", []int{2}},
 	OpJump:          {"OpJump", []int{2}},
+
+	// jump keeping the condition as the value, or pop it and go on
+	OpJumpIfFalseOrPop: {"OpJumpIfFalseOrPop", []int{2}},
+	OpJumpIfTrueOrPop:  {"OpJumpIfTrueOrPop", []int{2}},
+	// the same for a ?? b
+	OpJumpIfNotNullOrPop: {"OpJumpIfNotNullOrPop", []int{2}},
+	// jump keeping the value if it is null, or go on with it
+	OpJumpIfNull: {"OpJumpIfNull", []int{2}},
+
 	OpCall:          {"OpCall", []int{1}},
 	OpReturn:        {"OpReturn", []int{}},
 	OpReturnValue:   {"OpReturnValue", []int{}},
 
+	// OpTry enters the try block of handler operand of the function's
+	// handler table, OpEndTry leaves it; OpEndFinally ends a finally block,
+	// going on with the throw or return that entered it, if any
+	OpTry:        {"OpTry", []int{2}},
+	OpEndTry:     {"OpEndTry", []int{}},
+	OpEndFinally: {"OpEndFinally", []int{}},
+	OpThrow:      {"OpThrow", []int{}},
+
+	// OpDefer takes a function and its operand arguments off the stack to
+	// call them when the frame exits
+	OpDefer: {"OpDefer", []int{1}},
+
+	// OpYield suspends a generator, handing it the value on top of the
+	// stack, which null replaces once it resumes
+	OpYield: {"OpYield", []int{}},
+	// OpIter replaces the value on top of the stack with an iterator over
+	// it; OpIterNext pushes the next value of that iterator, or pops it
+	// and jumps to its operand once it is done
+	OpIter:     {"OpIter", []int{}},
+	OpIterNext: {"OpIterNext", []int{2}},
+
+	// OpSpawn takes a function and its operand arguments off the stack and
+	// pushes the task running the call
+	OpSpawn: {"OpSpawn", []int{1}},
+	// OpSelect takes a channel, a value and whether to send it of each of
+	// its first operand cases off the stack, pushes the value received by
+	// the case chosen and goes on to the jump of that case in the table of
+	// OpJumps after it; the last one is the default if its second operand
+	// is set
+	OpSelect: {"OpSelect", []int{1, 1}},
+
+	// OpMatchLiteral, OpMatchArray and OpMatchHash test a value against a
+	// pattern, replacing it with whether it matches: OpMatchLiteral takes
+	// the literal of the pattern off the stack too, OpMatchArray matches
+	// arrays of its first operand elements up to its second, or more if
+	// its third operand is set, and OpMatchHash takes its operand keys the
+	// hash must have. OpNoMatch raises the error of a match no arm of which
+	// matches the value on top of the stack, and OpMismatch that of a let
+	// whose pattern, the constant at its operand, it does not match.
+	OpMatchLiteral: {"OpMatchLiteral", []int{}},
+	OpMatchArray:   {"OpMatchArray", []int{2, 2, 1}},
+	OpMatchHash:    {"OpMatchHash", []int{2}},
+	OpNoMatch:      {"OpNoMatch", []int{}},
+	OpMismatch:     {"OpMismatch", []int{2}},
+
+	// OpDefault jumps to its second operand if the parameter in the local
+	// at its first operand got an argument, and goes on to compute its
+	// default otherwise. OpSpread and OpKeyword make the value on top of
+	// the stack the argument ...value of a call, and name: value with the
+	// name the constant at the operand of OpKeyword.
+	OpDefault: {"OpDefault", []int{1, 2}},
+	OpSpread:  {"OpSpread", []int{}},
+	OpKeyword: {"OpKeyword", []int{2}},
+
 	OpGetGlobal: {"OpGetGlobal", []int{2}},
 	OpSetGlobal: {"OpSetGlobal", []int{2}},
 	OpGetLocal:  {"OpGetLocal", []int{1}},
@@ -0,0 +1,89 @@
+package compiler
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/code"
+	"gwine/object"
+)
+
+// compileChain compiles node, an index, slice, selector or call, along with
+// the chain of them it applies to: in a?.b["c"](d) the call applies to an
+// index, applying to an optional selector. An optional link whose left side
+// is null jumps past the whole chain, leaving null as its value.
+func (c *Compiler) compileChain(node ast.Node) error {
+	var skips []int
+	if err := c.compileLink(node, &skips); err != nil {
+		return err
+	}
+	for _, pos := range skips {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// compileLink compiles a link of a chain after the links before it, adding
+// the jumps of optional links to skips.
+func (c *Compiler) compileLink(node ast.Node, skips *[]int) error {
+	left := func(left ast.Expression, optional bool) error {
+		if err := c.compileLink(left, skips); err != nil {
+			return err
+		}
+		if optional {
+			*skips = append(*skips, c.emit(code.OpJumpIfNull, 9999))
+		}
+		return nil
+	}
+
+	switch node := node.(type) {
+	case *ast.IndexExpression:
+		if err := left(node.Left, node.Optional); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+	case *ast.SelectorExpression:
+		if mod, ok := c.module(node.Left); ok {
+			return c.compileSelector(node, mod)
+		}
+		if err := left(node.Left, node.Optional); err != nil {
+			return err
+		}
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: node.Name.Value}))
+		c.emit(code.OpIndex)
+	case *ast.SliceExpression:
+		if err := left(node.Left, false); err != nil {
+			return err
+		}
+		for _, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			if err := c.Compile(bound); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSlice)
+	case *ast.CallExpression:
+		if ident, ok := node.Function.(*ast.Identifier); ok && (ident.Value == "quote" || ident.Value == "unquote") {
+			if _, ok := c.symbolTable.Resolve(ident.Value); !ok {
+				return fmt.Errorf("%s is only available inside macros", ident.Value)
+			}
+		}
+		if err := left(node.Function, false); err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			if err := c.Compile(a); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+	default:
+		return c.Compile(node)
+	}
+	return nil
+}
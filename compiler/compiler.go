@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"gwine/ast"
 	"gwine/code"
+	"gwine/module"
 	"gwine/object"
-	"sort"
 )
 
 type Compiler struct {
@@ -16,11 +16,17 @@ type Compiler struct {
 
 	symbolTable *SymbolTable
 	types       []object.Type
+
+	loader *module.Loader
+	file   string // file being compiled, imports are resolved against it
+
+	temporaries int // defined so far, see temporary
 }
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
 	Types        []object.Type
+	Handlers     []object.Handler // handler table of the top-level code
 }
 type EmittedInstruction struct {
 	Opcode   code.Opcode
@@ -30,6 +36,7 @@ type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	handlers            []object.Handler
 }
 
 func New() *Compiler {
@@ -64,10 +71,17 @@ func NewWithState(st *SymbolTable, constants []object.Object) *Compiler {
 		types:       make([]object.Type, 0),
 	}
 }
+// SetLoader sets the loader used for import statements and the path of the
+// file being compiled; file may be empty for code typed into the repl.
+func (c *Compiler) SetLoader(loader *module.Loader, file string) {
+	c.loader = loader
+	c.file = file
+}
 func (c *Compiler) ByteCode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		Handlers:     c.scopes[c.scopeIndex].handlers,
 	}
 }
 func (c *Compiler) Compile(node ast.Node) error {
@@ -80,8 +94,20 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 	case *ast.LetStatement:
-		symbol := c.symbolTable.Define(node.Name.Value)
-		err := c.Compile(node.Value)
+		shadows := c.symbolTable.shadows(node.Name.Value)
+		symbol, err := c.define(node.Name.Value)
+		if err != nil {
+			return err
+		}
+		if node.Const {
+			symbol = c.symbolTable.DefineConst(node.Name.Value, literal(node.Value))
+		}
+		unhide := func() {}
+		if shadows {
+			unhide = c.symbolTable.hide([]string{node.Name.Value})
+		}
+		err = c.Compile(node.Value)
+		unhide()
 		if err != nil {
 			return err
 		}
@@ -90,12 +116,52 @@ func (c *Compiler) Compile(node ast.Node) error {
 		} else if symbol.Scope == LocalScope {
 			c.emit(code.OpSetLocal, symbol.Index)
 		}
+	case *ast.LetPatternStatement:
+		return c.compileLetPattern(node)
 	case *ast.ReturnStatement:
 		err := c.Compile(node.ReturnValue)
 		if err != nil {
 			return err
 		}
 		c.emit(code.OpReturnValue)
+	case *ast.ThrowStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+	case *ast.DeferStatement:
+		if c.symbolTable.Outer == nil {
+			return fmt.Errorf("defer is only allowed inside functions")
+		}
+		err := c.Compile(node.Call.Function)
+		if err != nil {
+			return err
+		}
+		for _, a := range node.Call.Arguments {
+			err = c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpDefer, len(node.Call.Arguments))
+	case *ast.YieldExpression:
+		if c.symbolTable.Outer == nil {
+			return fmt.Errorf("yield is only allowed inside functions")
+		}
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpYield)
+	case *ast.ForExpression:
+		return c.compileFor(node)
+	case *ast.SpawnExpression:
+		return c.compileSpawn(node)
+	case *ast.SelectExpression:
+		return c.compileSelect(node)
+	case *ast.MatchExpression:
+		return c.compileMatch(node)
 	case *ast.ExpressionStatement:
 		err := c.Compile(node.Expression)
 		if err != nil {
@@ -139,10 +205,15 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpBang)
 		case "-":
 			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
 		default:
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return c.compileLogical(node)
+		}
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -151,88 +222,51 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		switch node.Operator {
-		case "+":
-			c.emit(code.OpAdd)
-		case "-":
-			c.emit(code.OpSub)
-		case "*":
-			c.emit(code.OpMul)
-		case "/":
-			c.emit(code.OpDiv)
-		case "==":
-			c.emit(code.OpEqual)
-		case "!=":
-			c.emit(code.OpNEqual)
-		case ">":
-			c.emit(code.OpGT)
-		case "<":
-			c.emit(code.OpLT)
-		default:
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
+		c.emit(op)
 	case *ast.IfExpression:
-		err := c.Compile(node.Condition)
-		if err != nil {
+		return c.compileIf(node)
+	case *ast.ConditionalExpression:
+		return c.compileConditional(node)
+	case *ast.TryExpression:
+		return c.compileTry(node)
+	case *ast.IndexExpression, *ast.SliceExpression, *ast.SelectorExpression, *ast.CallExpression:
+		return c.compileChain(node)
+	case *ast.SpreadExpression:
+		if err := c.Compile(node.Value); err != nil {
 			return err
 		}
-		jumpIfNotTruePos := c.emit(code.OpJumpIfNotTrue, 9999)
-
-		err = c.Compile(node.Consequence)
-		if err != nil {
+		c.emit(code.OpSpread)
+	case *ast.KeywordArgument:
+		if err := c.Compile(node.Value); err != nil {
 			return err
 		}
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
+		c.emit(code.OpKeyword, c.addConstant(&object.String{Value: node.Name}))
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.StringLiteral:
+		sv := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(sv))
+	case *ast.InterpolatedString:
+		if len(node.Parts) == 0 {
+			c.emit(code.OpConstant, c.addConstant(&object.String{}))
 		}
-
-		jumpPos := c.emit(code.OpJump, 9999)
-		consequenceEndpos := len(c.currentInstructions())
-		c.changeOperand(jumpIfNotTruePos, consequenceEndpos)
-
-		if node.Alternative != nil {
-
-			err := c.Compile(node.Alternative)
+		for i, part := range node.Parts {
+			err := c.Compile(part)
 			if err != nil {
 				return err
 			}
-
-			if c.lastInstructionIs(code.OpPop) {
-				c.removeLastPop()
+			if _, ok := part.(*ast.StringLiteral); !ok {
+				c.emit(code.OpStringify)
 			}
-		} else {
-			c.emit(code.OpNull)
-		}
-		alternativeEndpos := len(c.currentInstructions())
-		c.changeOperand(jumpPos, alternativeEndpos)
-	case *ast.IndexExpression:
-		err := c.Compile(node.Left)
-		if err != nil {
-			return err
-		}
-		err = c.Compile(node.Index)
-		if err != nil {
-			return err
-		}
-		c.emit(code.OpIndex)
-	case *ast.CallExpression:
-		err := c.Compile(node.Function)
-		if err != nil {
-			return err
-		}
-		for _, a := range node.Arguments {
-			err = c.Compile(a)
-			if err != nil {
-				return err
+			if i > 0 {
+				c.emit(code.OpAdd)
 			}
 		}
-		c.emit(code.OpCall, len(node.Arguments))
-	case *ast.IntegerLiteral:
-		integer := &object.Integer{Value: node.Value}
-		c.emit(code.OpConstant, c.addConstant(integer))
-	case *ast.StringLiteral:
-		sv := &object.String{Value: node.Value}
-		c.emit(code.OpConstant, c.addConstant(sv))
 	case *ast.ArrayLiteral:
 		for _, el := range node.Elements {
 			err := c.Compile(el)
@@ -242,15 +276,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.OpArray, len(node.Elements))
 	case *ast.HashLiteral:
-		keys := []ast.Expression{}
-		for k := range node.Paris {
-			keys = append(keys, k)
-		}
-		// for a ordered k-v
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-		for _, k := range keys {
+		for _, k := range node.Keys {
 			err := c.Compile(k)
 			if err != nil {
 				return err
@@ -267,8 +293,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if node.Name != "" {
 			c.symbolTable.DefineFunctionName(node.Name)
 		}
-		for _, p := range node.Parameters {
-			c.symbolTable.Define(p.Value)
+		if err := c.compileParameters(node); err != nil {
+			return err
 		}
 		err := c.Compile(node.Body)
 		if err != nil {
@@ -283,6 +309,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		freeSymbols := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numDefinitions
+		handlers := c.scopes[c.scopeIndex].handlers
 		ins := c.leaveScope()
 		for _, s := range freeSymbols {
 			c.loadSymbol(s)
@@ -291,9 +318,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 			Instructions:  ins,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Parameters),
+			Signature:     object.NewSignature(node),
+			Name:          node.Name,
+			Handlers:      handlers,
+			Generator:     node.IsGenerator(),
 		}
 		fnIndex := c.addConstant(compiledFn)
 		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+	case *ast.MacroLiteral:
+		return fmt.Errorf("macro literals must be bound by a top-level let")
+	case *ast.ImportStatement:
+		return c.compileImport(node)
+	case *ast.ExportStatement:
+		return c.compileExport(node)
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -305,6 +342,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if !ok {
 			return fmt.Errorf("undefined variable %s", node.Value)
 		}
+		if symbol.Scope == ModuleScope {
+			return fmt.Errorf("module %s can only be used as %s.name", node.Value, node.Value)
+		}
+		if symbol.Literal != nil {
+			return c.Compile(symbol.Literal)
+		}
 		c.loadSymbol(symbol)
 	}
 
@@ -410,6 +453,60 @@ func (c *Compiler) loadSymbol(symbol Symbol) {
 
 	}
 }
+// compileParameters defines the parameters of fn as its first locals and
+// compiles the computation of the defaults of those that get no argument.
+// A default sees the parameters before it only, as in the evaluator.
+func (c *Compiler) compileParameters(fn *ast.FunctionLiteral) error {
+	names := make([]string, len(fn.Parameters))
+	for i, p := range fn.Parameters {
+		c.symbolTable.Define(p.Value)
+		names[i] = p.Value
+	}
+	for i := range fn.Parameters {
+		dflt := fn.Default(i)
+		if dflt == nil {
+			continue
+		}
+		pos := c.emit(code.OpDefault, i, 9999)
+		unhide := c.symbolTable.hide(names[i:])
+		err := c.Compile(dflt)
+		unhide()
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpSetLocal, i)
+		c.replaceInstruction(pos, code.Make(code.OpDefault, i, len(c.currentInstructions())))
+	}
+	return nil
+}
+
+// define binds name in the current scope like SymbolTable.Define, unless
+// it is a constant there.
+func (c *Compiler) define(name string) (Symbol, error) {
+	if c.symbolTable.Constant(name) {
+		return Symbol{}, fmt.Errorf("cannot reassign constant %s", name)
+	}
+	return c.symbolTable.Define(name), nil
+}
+
+// literal returns value if it is an integer, string or boolean literal, and
+// nil otherwise.
+func literal(value ast.Expression) ast.Expression {
+	switch value.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return value
+	}
+	return nil
+}
+
+// setSymbol sets the variable symbol to the value on top of the stack.
+func (c *Compiler) setSymbol(symbol Symbol) {
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+}
 
 func (c *Compiler) CompileMethods(vars []*object.Member, literals []*ast.FunctionLiteral) ([]*object.CompiledFunction, error) {
 	compiledFns := []*object.CompiledFunction{}
@@ -421,8 +518,8 @@ func (c *Compiler) CompileMethods(vars []*object.Member, literals []*ast.Functio
 		if literal.Name != "" {
 			c.symbolTable.DefineFunctionName(literal.Name)
 		}
-		for _, p := range literal.Parameters {
-			c.symbolTable.Define(p.Value)
+		if err := c.compileParameters(literal); err != nil {
+			return nil, err
 		}
 		err := c.Compile(literal.Body)
 		if err != nil {
@@ -436,6 +533,7 @@ func (c *Compiler) CompileMethods(vars []*object.Member, literals []*ast.Functio
 			c.emit(code.OpReturn)
 		}
 		numLocals := c.symbolTable.numDefinitions
+		handlers := c.scopes[c.scopeIndex].handlers
 
 		ins := c.leaveScope()
 
@@ -443,6 +541,10 @@ func (c *Compiler) CompileMethods(vars []*object.Member, literals []*ast.Functio
 			Instructions:  ins,
 			NumLocals:     numLocals,
 			NumParameters: len(literal.Parameters),
+			Signature:     object.NewSignature(literal),
+			Name:          literal.Name,
+			Handlers:      handlers,
+			Generator:     literal.IsGenerator(),
 		}
 
 		compiledFns = append(compiledFns, compiledFn)
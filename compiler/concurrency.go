@@ -0,0 +1,93 @@
+package compiler
+
+import (
+	"gwine/ast"
+	"gwine/code"
+)
+
+// compileSpawn compiles spawn f(args) like the call, with OpSpawn making it.
+func (c *Compiler) compileSpawn(node *ast.SpawnExpression) error {
+	if err := c.Compile(node.Call.Function); err != nil {
+		return err
+	}
+	for _, a := range node.Call.Arguments {
+		if err := c.Compile(a); err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpSpawn, len(node.Call.Arguments))
+	return nil
+}
+
+// compileSelect compiles select { case x = recv(ch) { b } case send(ch, v)
+// { s } default { d } } to
+//
+//	ch; OpNull; OpFalse; ch; v; OpTrue; OpSelect 2 1
+//	OpJump B; OpJump S; OpJump D
+//	B: set x; b; OpJump E
+//	S: OpPop; s; OpJump E
+//	D: OpPop; d
+//	E:
+//
+// where OpSelect pushes the value received, null for a send or the default,
+// and goes on to the jump of the case chosen.
+func (c *Compiler) compileSelect(node *ast.SelectExpression) error {
+	for _, sc := range node.Cases {
+		if err := c.Compile(sc.Call.Arguments[0]); err != nil {
+			return err
+		}
+		if sc.Send() {
+			if err := c.Compile(sc.Call.Arguments[1]); err != nil {
+				return err
+			}
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpNull)
+			c.emit(code.OpFalse)
+		}
+	}
+	hasDefault := 0
+	if node.Default != nil {
+		hasDefault = 1
+	}
+	c.emit(code.OpSelect, len(node.Cases), hasDefault)
+
+	table := make([]int, len(node.Cases)+hasDefault)
+	for i := range table {
+		table[i] = c.emit(code.OpJump, 9999)
+	}
+	var ends []int
+	for i, sc := range node.Cases {
+		c.changeOperand(table[i], len(c.currentInstructions()))
+		if sc.Variable != nil {
+			symbol, err := c.define(sc.Variable.Value)
+			if err != nil {
+				return err
+			}
+			if symbol.Scope == GlobalScope {
+				c.emit(code.OpSetGlobal, symbol.Index)
+			} else {
+				c.emit(code.OpSetLocal, symbol.Index)
+			}
+		} else {
+			c.emit(code.OpPop)
+		}
+		if err := c.compileBlockValue(sc.Body); err != nil {
+			return err
+		}
+		if i < len(table)-1 {
+			ends = append(ends, c.emit(code.OpJump, 9999))
+		}
+	}
+	if node.Default != nil {
+		c.changeOperand(table[len(table)-1], len(c.currentInstructions()))
+		c.emit(code.OpPop)
+		if err := c.compileBlockValue(node.Default); err != nil {
+			return err
+		}
+	}
+	for _, pos := range ends {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
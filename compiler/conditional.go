@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"gwine/ast"
+	"gwine/code"
+)
+
+// compileIf compiles if (c1) { b1 } else if (c2) { b2 } else { b3 } to
+//
+//	c1; OpJumpIfNotTrue A; b1; OpJump E
+//	A: c2; OpJumpIfNotTrue B; b2; OpJump E
+//	B: b3
+//	E:
+//
+// with OpNull in place of b3 when there is no else, so that a chain of any
+// length stays as flat as a single if.
+func (c *Compiler) compileIf(node *ast.IfExpression) error {
+	var ends []int
+	branch := func(condition ast.Expression, consequence *ast.BlockStatement) error {
+		if err := c.Compile(condition); err != nil {
+			return err
+		}
+		next := c.emit(code.OpJumpIfNotTrue, 9999)
+		if err := c.Compile(consequence); err != nil {
+			return err
+		}
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		}
+		ends = append(ends, c.emit(code.OpJump, 9999))
+		c.changeOperand(next, len(c.currentInstructions()))
+		return nil
+	}
+
+	if err := branch(node.Condition, node.Consequence); err != nil {
+		return err
+	}
+	for _, ei := range node.ElseIfs {
+		if err := branch(ei.Condition, ei.Consequence); err != nil {
+			return err
+		}
+	}
+	if node.Alternative != nil {
+		if err := c.Compile(node.Alternative); err != nil {
+			return err
+		}
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		}
+	} else {
+		c.emit(code.OpNull)
+	}
+	for _, pos := range ends {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// compileConditional compiles c ? a : b to
+//
+//	c; OpJumpIfNotTrue A; a; OpJump E
+//	A: b
+//	E:
+func (c *Compiler) compileConditional(node *ast.ConditionalExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+	alternative := c.emit(code.OpJumpIfNotTrue, 9999)
+	if err := c.Compile(node.Consequence); err != nil {
+		return err
+	}
+	end := c.emit(code.OpJump, 9999)
+	c.changeOperand(alternative, len(c.currentInstructions()))
+	if err := c.Compile(node.Alternative); err != nil {
+		return err
+	}
+	c.changeOperand(end, len(c.currentInstructions()))
+	return nil
+}
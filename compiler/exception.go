@@ -0,0 +1,77 @@
+package compiler
+
+import (
+	"gwine/ast"
+	"gwine/code"
+	"gwine/object"
+)
+
+// compileTry compiles try { b } catch (e) { c } finally { f } to
+//
+//	OpTry h; b; OpEndTry; OpJump F
+//	C: set e; c; OpEndTry
+//	F: f; OpEndFinally
+//
+// where entry h of the handler table holds C and F. The vm enters C with
+// the exception pushed, after unwinding the stack to where OpTry left it,
+// and keeps the try entered during c so that f also runs if c throws. f
+// runs with the value of b or c on the stack when it is entered normally,
+// and with the throw or return to go on with otherwise.
+func (c *Compiler) compileTry(node *ast.TryExpression) error {
+	index := len(c.scopes[c.scopeIndex].handlers)
+	c.scopes[c.scopeIndex].handlers = append(c.scopes[c.scopeIndex].handlers, object.Handler{Catch: -1, Finally: -1})
+	handler := func() *object.Handler {
+		// scopes may grow while compiling the blocks
+		return &c.scopes[c.scopeIndex].handlers[index]
+	}
+
+	c.emit(code.OpTry, index)
+	if err := c.compileBlockValue(node.Block); err != nil {
+		return err
+	}
+	c.emit(code.OpEndTry)
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	if node.Catch != nil {
+		handler().Catch = len(c.currentInstructions())
+		symbol, err := c.define(node.Param.Value)
+		if err != nil {
+			return err
+		}
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+		if err := c.compileBlockValue(node.Catch); err != nil {
+			return err
+		}
+		if node.Finally != nil {
+			c.emit(code.OpEndTry)
+		}
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	if node.Finally != nil {
+		handler().Finally = len(c.currentInstructions())
+		if err := c.Compile(node.Finally); err != nil {
+			return err
+		}
+		c.emit(code.OpEndFinally)
+	}
+	return nil
+}
+
+// compileBlockValue compiles block leaving the value of its last statement
+// on the stack, or null if that is not an expression.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package compiler
+
+import (
+	"gwine/ast"
+	"gwine/code"
+)
+
+// compileFor compiles a for expression to
+//
+//	iterable; OpIter
+//	L: OpIterNext E; set x; body; OpJump L
+//	E: OpNull
+//
+// where OpIterNext pushes the next value, or pops the iterator and jumps to
+// E once there is none left.
+func (c *Compiler) compileFor(node *ast.ForExpression) error {
+	if err := c.Compile(node.Iterable); err != nil {
+		return err
+	}
+	c.emit(code.OpIter)
+	loop := len(c.currentInstructions())
+	nextPos := c.emit(code.OpIterNext, 9999)
+
+	symbol, err := c.define(node.Variable.Value)
+	if err != nil {
+		return err
+	}
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, loop)
+	c.changeOperand(nextPos, len(c.currentInstructions()))
+	c.emit(code.OpNull)
+	return nil
+}
@@ -0,0 +1,225 @@
+package compiler
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/code"
+	"gwine/object"
+)
+
+// compileMatch compiles match (s) { p1 if g => b1, p2 => b2 } to
+//
+//	s; set t
+//	test p1 against t, failing to A; g; OpJumpIfNotTrue A; b1; OpJump E
+//	A: test p2 against t, failing to F; b2; OpJump E
+//	F: get t; OpNoMatch
+//	E:
+//
+// where t is a variable of the compiler's own holding the subject.
+func (c *Compiler) compileMatch(node *ast.MatchExpression) error {
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
+	subject := c.temporary()
+	c.setSymbol(subject)
+
+	var ends []int
+	for _, arm := range node.Arms {
+		var fails []int
+		if err := c.compilePattern(arm.Pattern, subject, &fails); err != nil {
+			return err
+		}
+		if arm.Guard != nil {
+			if err := c.Compile(arm.Guard); err != nil {
+				return err
+			}
+			fails = append(fails, c.emit(code.OpJumpIfNotTrue, 9999))
+		}
+		if err := c.compileBlockValue(arm.Body); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(code.OpJump, 9999))
+		for _, pos := range fails {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+	}
+	c.loadSymbol(subject)
+	c.emit(code.OpNoMatch)
+	for _, pos := range ends {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// compilePattern compiles the test of pattern against the value of
+// variable v, binding its names as it goes. The jumps taken when the value
+// does not match are added to fails, for the caller to set where to.
+func (c *Compiler) compilePattern(pattern ast.Pattern, v Symbol, fails *[]int) error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			c.loadSymbol(v)
+			symbol, err := c.define(pattern.Value)
+			if err != nil {
+				return err
+			}
+			c.setSymbol(symbol)
+		}
+	case *ast.LiteralPattern:
+		c.loadSymbol(v)
+		if err := c.Compile(pattern.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpMatchLiteral)
+		*fails = append(*fails, c.emit(code.OpJumpIfNotTrue, 9999))
+	case *ast.ArrayPattern:
+		rest := 0
+		if pattern.Rest != nil {
+			rest = 1
+		}
+		c.loadSymbol(v)
+		c.emit(code.OpMatchArray, ast.Required(pattern.Elements), len(pattern.Elements), rest)
+		*fails = append(*fails, c.emit(code.OpJumpIfNotTrue, 9999))
+		for i, element := range pattern.Elements {
+			index := &object.Integer{Value: int64(i)}
+			err := c.compileElement(element, v, func() error {
+				c.emit(code.OpConstant, c.addConstant(index))
+				return nil
+			}, func() error {
+				c.loadSymbol(v)
+				c.emit(code.OpMatchArray, i+1, i+1, 1)
+				return nil
+			}, fails)
+			if err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			c.loadSymbol(v)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(len(pattern.Elements))}))
+			c.emit(code.OpNull)
+			c.emit(code.OpNull)
+			c.emit(code.OpSlice)
+			symbol, err := c.define(pattern.Rest.Value)
+			if err != nil {
+				return err
+			}
+			c.setSymbol(symbol)
+		}
+	case *ast.HashPattern:
+		c.loadSymbol(v)
+		for i, k := range pattern.Keys {
+			if _, ok := pattern.Values[i].(*ast.DefaultPattern); ok {
+				continue
+			}
+			if err := c.Compile(k); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpMatchHash, ast.Required(pattern.Values))
+		*fails = append(*fails, c.emit(code.OpJumpIfNotTrue, 9999))
+		for i, k := range pattern.Keys {
+			err := c.compileElement(pattern.Values[i], v, func() error {
+				return c.Compile(k)
+			}, func() error {
+				c.loadSymbol(v)
+				if err := c.Compile(k); err != nil {
+					return err
+				}
+				c.emit(code.OpMatchHash, 1)
+				return nil
+			}, fails)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown pattern %s", pattern.String())
+	}
+	return nil
+}
+
+// compileElement compiles the test of pattern against v[i], where index
+// compiles i. If pattern has a default, has compiles whether v has an i,
+// and the default stands for v[i] if it does not.
+func (c *Compiler) compileElement(pattern ast.Pattern, v Symbol, index, has func() error, fails *[]int) error {
+	dflt, _ := pattern.(*ast.DefaultPattern)
+	if dflt != nil {
+		pattern = dflt.Pattern
+	}
+	ident, _ := pattern.(*ast.Identifier)
+	if ident != nil && ident.Value == "_" && dflt == nil {
+		return nil
+	}
+
+	var missing int
+	if dflt != nil {
+		if err := has(); err != nil {
+			return err
+		}
+		missing = c.emit(code.OpJumpIfNotTrue, 9999)
+	}
+	c.loadSymbol(v)
+	if err := index(); err != nil {
+		return err
+	}
+	c.emit(code.OpIndex)
+	if dflt != nil {
+		end := c.emit(code.OpJump, 9999)
+		c.changeOperand(missing, len(c.currentInstructions()))
+		if err := c.Compile(dflt.Default); err != nil {
+			return err
+		}
+		c.changeOperand(end, len(c.currentInstructions()))
+	}
+
+	switch {
+	case ident != nil && ident.Value == "_":
+		c.emit(code.OpPop)
+		return nil
+	case ident != nil:
+		symbol, err := c.define(ident.Value)
+		if err != nil {
+			return err
+		}
+		c.setSymbol(symbol)
+		return nil
+	}
+	element := c.temporary()
+	c.setSymbol(element)
+	return c.compilePattern(pattern, element, fails)
+}
+
+// compileLetPattern compiles let p = v; to
+//
+//	v; set t
+//	test p against t, failing to F; OpJump E
+//	F: get t; OpMismatch p
+//	E:
+func (c *Compiler) compileLetPattern(node *ast.LetPatternStatement) error {
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+	value := c.temporary()
+	c.setSymbol(value)
+
+	var fails []int
+	if err := c.compilePattern(node.Pattern, value, &fails); err != nil {
+		return err
+	}
+	end := c.emit(code.OpJump, 9999)
+	for _, pos := range fails {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	c.loadSymbol(value)
+	c.emit(code.OpMismatch, c.addConstant(&object.String{Value: node.Pattern.String()}))
+	c.changeOperand(end, len(c.currentInstructions()))
+	return nil
+}
+
+// temporary defines a variable no code can name, for the compiler to keep
+// a value in.
+func (c *Compiler) temporary() Symbol {
+	c.temporaries++
+	return c.symbolTable.Define(fmt.Sprintf("%d temporary", c.temporaries))
+}
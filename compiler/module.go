@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/module"
+)
+
+// compileImport compiles the imported module the first time it is seen and
+// binds its namespace, or the selected exports, in the current table.
+//
+// A module is compiled in place, into its own global table: its top-level
+// code runs when the first import of it runs, and its globals share the
+// program's global store at indices no other table uses.
+func (c *Compiler) compileImport(node *ast.ImportStatement) error {
+	if c.symbolTable.Outer != nil {
+		return fmt.Errorf("import is only allowed at the top level")
+	}
+	if c.loader == nil {
+		c.loader = module.NewLoader()
+	}
+	path, err := c.loader.Resolve(node.Path, c.file)
+	if err != nil {
+		return err
+	}
+
+	globals := c.symbolTable.globals
+	index, ok := globals.byPath[path]
+	if !ok {
+		index, err = c.compileModule(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	mod := globals.modules[index]
+	names := node.Names
+	if node.Alias != nil {
+		names = append([]*ast.Identifier{node.Alias}, names...)
+	}
+	for _, name := range names {
+		if c.symbolTable.Constant(name.Value) {
+			return fmt.Errorf("cannot reassign constant %s", name.Value)
+		}
+	}
+	if node.Alias != nil {
+		c.symbolTable.DefineModule(index, node.Alias.Value)
+	}
+	for _, name := range node.Names {
+		sym, ok := mod.table.Exports[name.Value]
+		if !ok {
+			return fmt.Errorf("%s does not export %s", node.Path, name.Value)
+		}
+		c.symbolTable.DefineAlias(name.Value, sym)
+	}
+	return nil
+}
+func (c *Compiler) compileModule(path string) (int, error) {
+	if err := c.loader.Begin(path); err != nil {
+		return 0, err
+	}
+	defer c.loader.End(path)
+
+	program, err := c.loader.Load(path)
+	if err != nil {
+		return 0, err
+	}
+
+	outer, outerFile := c.symbolTable, c.file
+	c.symbolTable, c.file = NewModuleSymbolTable(outer), path
+	err = c.Compile(program)
+	table := c.symbolTable
+	c.symbolTable, c.file = outer, outerFile
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+
+	globals := outer.globals
+	globals.modules = append(globals.modules, &compiledModule{path: path, table: table})
+	globals.byPath[path] = len(globals.modules) - 1
+	return len(globals.modules) - 1, nil
+}
+func (c *Compiler) compileExport(node *ast.ExportStatement) error {
+	if c.symbolTable.Outer != nil {
+		return fmt.Errorf("export is only allowed at the top level")
+	}
+	if err := c.Compile(node.Statement); err != nil {
+		return err
+	}
+	name := node.Statement.Name.Value
+	sym, _ := c.symbolTable.Resolve(name)
+	c.symbolTable.Exports[name] = sym
+	return nil
+}
+
+// module reports the module that expr names, if it is the name of an
+// imported module.
+func (c *Compiler) module(expr ast.Expression) (*compiledModule, bool) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	sym, ok := c.symbolTable.Resolve(ident.Value)
+	if !ok || sym.Scope != ModuleScope {
+		return nil, false
+	}
+	return c.symbolTable.globals.modules[sym.Index], true
+}
+
+// compileSelector compiles module.name to a load of the exported global.
+func (c *Compiler) compileSelector(node *ast.SelectorExpression, mod *compiledModule) error {
+	exported, ok := mod.table.Exports[node.Name.Value]
+	if !ok {
+		return fmt.Errorf("module %s does not export %s", node.Left.String(), node.Name.Value)
+	}
+	c.loadSymbol(exported)
+	return nil
+}
@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"gwine/ast"
+	"gwine/code"
+)
+
+// infixOpcodes are the opcodes of the infix operators that evaluate both
+// operands.
+var infixOpcodes = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"&":  code.OpBitAnd,
+	"|":  code.OpBitOr,
+	"^":  code.OpBitXor,
+	"<<": code.OpShl,
+	">>": code.OpShr,
+	"==": code.OpEqual,
+	"!=": code.OpNEqual,
+	">":  code.OpGT,
+	"<":  code.OpLT,
+	">=": code.OpGE,
+	"<=": code.OpLE,
+}
+
+// compileLogical compiles a && b, a || b and a ?? b so that b only runs when
+// a does not decide the result; the value is that of the operand evaluated
+// last.
+func (c *Compiler) compileLogical(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+	op := code.OpJumpIfFalseOrPop
+	switch node.Operator {
+	case "||":
+		op = code.OpJumpIfTrueOrPop
+	case "??":
+		op = code.OpJumpIfNotNullOrPop
+	}
+	jumpPos := c.emit(op, 9999)
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
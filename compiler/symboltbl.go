@@ -1,6 +1,9 @@
 package compiler
 
-import "gwine/object"
+import (
+	"gwine/ast"
+	"gwine/object"
+)
 
 type SymbolScope string
 
@@ -11,26 +14,55 @@ const (
 	FreeScope     SymbolScope = "FREE"
 	FunctionScope SymbolScope = "FUNCTION"
 	StructScope   SymbolScope = "STRUCT"
+	ModuleScope   SymbolScope = "MODULE"
 )
 
 type Symbol struct {
 	Name  string
 	Scope SymbolScope
 	Index int
+
+	// Const is set on the symbol of a const, and Literal holds its value
+	// when that is a literal, which uses of the symbol compile to.
+	Const   bool
+	Literal ast.Expression
 }
 
 type SymbolTable struct {
 	store          map[string]Symbol
 	numDefinitions int
+	defined        map[string]Symbol // by Define, which reuses them
 
 	FreeSymbols []Symbol
 	Outer       *SymbolTable
+
+	// Exports holds the global symbols marked with `export`.
+	Exports map[string]Symbol
+
+	globals *globalState
+}
+
+// globalState is shared by the global tables of a program and of every
+// module it imports: their globals live in one store, so indices are handed
+// out from a single counter.
+type globalState struct {
+	numGlobals int
+	modules    []*compiledModule
+	byPath     map[string]int
+}
+
+// compiledModule is an imported file compiled into its own global table.
+type compiledModule struct {
+	path  string
+	table *SymbolTable
 }
 
 func NewSymbolTable() *SymbolTable {
 	return &SymbolTable{
 		store:       make(map[string]Symbol),
 		FreeSymbols: []Symbol{},
+		Exports:     make(map[string]Symbol),
+		globals:     &globalState{byPath: make(map[string]int)},
 	}
 }
 func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
@@ -38,13 +70,36 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 		store:       make(map[string]Symbol),
 		FreeSymbols: []Symbol{},
 		Outer:       outer,
+		Exports:     make(map[string]Symbol),
+		globals:     outer.globals,
 	}
 }
+
+// NewModuleSymbolTable returns a global table for a module imported by the
+// program st belongs to. It sees the builtins of st but none of its globals.
+func NewModuleSymbolTable(st *SymbolTable) *SymbolTable {
+	for st.Outer != nil {
+		st = st.Outer
+	}
+	mt := &SymbolTable{
+		store:       make(map[string]Symbol),
+		FreeSymbols: []Symbol{},
+		Exports:     make(map[string]Symbol),
+		globals:     st.globals,
+	}
+	for name, sym := range st.store {
+		if sym.Scope == BuiltinScope {
+			mt.store[name] = sym
+		}
+	}
+	return mt
+}
 func NewTypeInnerSymbolTable(inners []*object.Member) *SymbolTable {
 
 	st := &SymbolTable{
 		store: make(map[string]Symbol),
 		FreeSymbols: []Symbol{},
+		globals: &globalState{byPath: make(map[string]int)},
 	}
 	for _ , i := range inners{
 		st.DefineInner(i.Name)	
@@ -57,26 +112,106 @@ func (st *SymbolTable) DefineInner(name string) {
 	st.numDefinitions ++
 	st.store[name] = symbol
 }
+// Define binds name to a new global or local. Defining a name again reuses
+// its slot while it still refers to it, so that let x = x + 1 reads the old
+// value and a let run again in a loop updates the same variable, as in the
+// evaluator; a new local shadowing a variable of an outer table is left to
+// shadows.
 func (st *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := st.store[name]; ok && st.defined[name] == symbol {
+		return symbol
+	}
 	symbol := Symbol{Name: name, Index: st.numDefinitions}
 	if st.Outer == nil {
 		symbol.Scope = GlobalScope
+		symbol.Index = st.globals.numGlobals
+		st.globals.numGlobals++
 	} else {
 		symbol.Scope = LocalScope
 	}
 	st.numDefinitions++
 	st.store[name] = symbol
+	if st.defined == nil {
+		st.defined = make(map[string]Symbol)
+	}
+	st.defined[name] = symbol
+	return symbol
+}
+
+// shadows reports whether defining name in st makes a new local hiding a
+// variable of an outer table, the one let x = x + 1 reads.
+func (st *SymbolTable) shadows(name string) bool {
+	if st.Outer == nil {
+		return false
+	}
+	if symbol, ok := st.store[name]; ok && st.defined[name] == symbol {
+		return false
+	}
+	for outer := st.Outer; outer != nil; outer = outer.Outer {
+		if _, ok := outer.store[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DefineConst binds name like Define, as a constant whose value is literal,
+// or nil if it is not a literal.
+func (st *SymbolTable) DefineConst(name string, literal ast.Expression) Symbol {
+	symbol := st.Define(name)
+	symbol.Const, symbol.Literal = true, literal
+	st.store[name] = symbol
+	st.defined[name] = symbol
 	return symbol
 }
+
+// Constant reports whether name is a constant of st itself rather than one
+// captured from an outer table, which a binding in st merely shadows.
+func (st *SymbolTable) Constant(name string) bool {
+	symbol, ok := st.store[name]
+	return ok && symbol.Const && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope)
+}
+
+// hide takes names out of st until the function it returns puts them back,
+// so that code compiled meanwhile resolves them in the outer tables.
+func (st *SymbolTable) hide(names []string) func() {
+	hidden := map[string]Symbol{}
+	for _, name := range names {
+		if symbol, ok := st.store[name]; ok {
+			hidden[name] = symbol
+			delete(st.store, name)
+		}
+	}
+	return func() {
+		for name, symbol := range hidden {
+			st.store[name] = symbol
+		}
+	}
+}
 func (st *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
 	st.store[name] = symbol
 	return symbol
 }
+// DefineModule binds name to the module with the given index, so that
+// name.x resolves to the export x of that module.
+func (st *SymbolTable) DefineModule(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: ModuleScope}
+	st.store[name] = symbol
+	return symbol
+}
+
+// DefineAlias binds name to an existing symbol, as a selective import does.
+// The alias is not a constant even if the original is.
+func (st *SymbolTable) DefineAlias(name string, original Symbol) Symbol {
+	original.Const = false
+	st.store[name] = original
+	return original
+}
 func (st *SymbolTable) defineFree(original Symbol) Symbol {
 	st.FreeSymbols = append(st.FreeSymbols, original)
 
-	symbol := Symbol{Name: original.Name, Index: len(st.FreeSymbols) - 1, Scope: FreeScope}
+	symbol := Symbol{Name: original.Name, Index: len(st.FreeSymbols) - 1, Scope: FreeScope, Literal: original.Literal}
 	st.store[original.Name] = symbol
 	return symbol
 }
@@ -93,7 +228,7 @@ func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
 		if !ok {
 			return sym, ok
 		}
-		if sym.Scope == GlobalScope || sym.Scope == BuiltinScope {
+		if sym.Scope == GlobalScope || sym.Scope == BuiltinScope || sym.Scope == ModuleScope {
 			return sym, ok
 		}
 
@@ -0,0 +1,196 @@
+// Package difftest runs gwine programs on both the tree-walking evaluator and
+// the compiler and vm, so that tests can check that the two engines agree.
+package difftest
+
+import (
+	"bytes"
+	"fmt"
+	"gwine/ast"
+	"gwine/compiler"
+	"gwine/evaluator"
+	"gwine/lexer"
+	"gwine/module"
+	"gwine/object"
+	"gwine/parser"
+	"gwine/vm"
+	"strings"
+)
+
+// Error kinds of a Result. The engines word some errors differently, which
+// kind tells apart; errors of no other kind are Failures.
+const (
+	ParseError     = "parse"
+	TypeError      = "type"      // an operand, argument or callee of the wrong type
+	DivisionByZero = "division"  // a division or modulo by zero
+	Undefined      = "undefined" // a name not bound, or read before it is set
+	Arguments      = "arguments" // a call with arguments its function does not take
+	Uncaught       = "uncaught"  // a throw no try caught
+	Failure        = "error"
+	Panic          = "panic"
+)
+
+// kinds maps a part of error messages to the kind of error they report.
+var kinds = []struct{ part, kind string }{
+	{"uncaught exception: ", Uncaught},
+	{"division by zero", DivisionByZero},
+	{"modulo by zero", DivisionByZero},
+	{"identifier not found ", Undefined},
+	{"undefined variable ", Undefined},
+	{"variable used before it is set", Undefined},
+	{"wrong number of arguments ", Arguments},
+	{" is missing an argument ", Arguments},
+	{" has no parameter ", Arguments},
+	{" got two values for parameter ", Arguments},
+	{" takes no keyword arguments", Arguments},
+	{"type mismatch: ", TypeError},
+	{"unknown operator", TypeError},
+	{"unknown operand", TypeError},
+	{"not a function", TypeError},
+	{"operator not supported ", TypeError},
+	{"unusable as hash key ", TypeError},
+	{" must be ", TypeError},
+	{" is not iterable", TypeError},
+	{"cannot spread ", TypeError},
+}
+
+// failure returns the Result of an error with message, classified.
+func failure(output, message string) Result {
+	kind := Failure
+	for _, k := range kinds {
+		if strings.Contains(message, k.part) {
+			kind = k.kind
+			break
+		}
+	}
+	return Result{Output: output, Error: kind, Message: message}
+}
+
+// Result is what running a program did.
+type Result struct {
+	Output  string // everything the program wrote
+	Value   string // the value of the final expression statement, if any
+	Error   string // "" or one of the error kinds
+	Message string
+	Static  bool // the vm failed to compile the program, so it wrote nothing
+}
+
+// Equal reports whether a and b agree. Errors agree if they are of the same
+// kind, Failures only with the same message, and if they came after the
+// same output; the vm reports at compile time what the evaluator only finds
+// while running, after some output, which is then left out. A panic never
+// agrees with anything, not even another panic.
+func Equal(a, b Result) bool {
+	if a.Error == Panic || b.Error == Panic || a.Error != b.Error {
+		return false
+	}
+	if a.Error == Failure && a.Message != b.Message {
+		return false
+	}
+	if a.Error != "" {
+		return a.Static || b.Static || a.Output == b.Output
+	}
+	return a.Output == b.Output && a.Value == b.Value
+}
+
+// String formats r the way golden files hold it.
+func (r Result) String() string {
+	var out bytes.Buffer
+	out.WriteString("output:\n")
+	out.WriteString(r.Output)
+	if r.Output != "" && !strings.HasSuffix(r.Output, "\n") {
+		out.WriteString("\n")
+	}
+	if r.Error != "" {
+		out.WriteString("error: " + r.Error + ": " + r.Message + "\n")
+		return out.String()
+	}
+	out.WriteString("value: " + r.Value + "\n")
+	return out.String()
+}
+
+// parse parses src and expands its macros.
+func parse(src string) (*ast.Program, *Result) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &Result{Error: ParseError, Message: strings.Join(p.Errors(), "; ")}
+	}
+	macros := object.NewEnvironment()
+	evaluator.DefineMacros(program, macros)
+	expanded, err := evaluator.ExpandMacros(program, macros)
+	if err != nil {
+		r := failure("", err.Error())
+		return nil, &r
+	}
+	return expanded.(*ast.Program), nil
+}
+
+func endsInExpression(program *ast.Program) bool {
+	if len(program.Statements) == 0 {
+		return false
+	}
+	_, ok := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement)
+	return ok
+}
+
+func recoverPanic(r *Result, out *bytes.Buffer) {
+	if p := recover(); p != nil {
+		*r = Result{Output: out.String(), Error: Panic, Message: fmt.Sprint(p)}
+	}
+}
+
+// Run runs src on the vm. Imports are resolved from the working directory.
+func Run(src string) (r Result) {
+	program, failed := parse(src)
+	if failed != nil {
+		return *failed
+	}
+	var out bytes.Buffer
+	defer recoverPanic(&r, &out)
+
+	symboltbl := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symboltbl.DefineBuiltin(i, v.Name)
+	}
+	comp := compiler.NewWithState(symboltbl, []object.Object{})
+	comp.SetLoader(module.NewLoader(), "")
+	if err := comp.Compile(program); err != nil {
+		r = failure("", err.Error())
+		r.Static = true
+		return r
+	}
+	machine := vm.NewWithGlobalStore(comp.ByteCode(), vm.NewGlobals())
+	machine.SetStreams(strings.NewReader(""), &out)
+	if err := machine.Run(); err != nil {
+		return failure(out.String(), err.Error())
+	}
+	r = Result{Output: out.String()}
+	if endsInExpression(program) {
+		r.Value = machine.LastPoped().Inspect()
+	}
+	return r
+}
+
+// Eval runs src on the evaluator. Imports are resolved from the working
+// directory.
+func Eval(src string) (r Result) {
+	program, failed := parse(src)
+	if failed != nil {
+		return *failed
+	}
+	var out bytes.Buffer
+	defer recoverPanic(&r, &out)
+
+	env := object.NewEnvironment()
+	env.SetModules(object.NewModules(module.NewLoader()), "")
+	env.SetStreams(strings.NewReader(""), &out)
+	result := evaluator.Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		return failure(out.String(), err.Message)
+	}
+	r = Result{Output: out.String()}
+	if endsInExpression(program) && result != nil {
+		r.Value = result.Inspect()
+	}
+	return r
+}
@@ -0,0 +1,78 @@
+package difftest
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestCorpus runs every testdata/*.gw program on both engines, checks that
+// they agree and that the result matches testdata/*.golden. The golden file
+// of a failing program holds what each engine reported, as they word their
+// errors differently.
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.gw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".gw")
+		t.Run(name, func(t *testing.T) {
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			onVM, onEval := Run(string(src)), Eval(string(src))
+			if !Equal(onVM, onEval) {
+				t.Fatalf("engines disagree\nvm:\n%sevaluator:\n%s", onVM, onEval)
+			}
+			got := onVM.String()
+			if onVM.Error != "" {
+				got = "vm:\n" + got + "evaluator:\n" + onEval.String()
+			}
+
+			golden := strings.TrimSuffix(file, ".gw") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("result differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b     Result
+		expected bool
+	}{
+		{Result{Output: "1\n", Value: "2"}, Result{Output: "1\n", Value: "2"}, true},
+		{Result{Output: "1\n", Value: "2"}, Result{Output: "1\n", Value: "3"}, false},
+		{Result{Error: Failure, Message: "a"}, Result{Error: Failure, Message: "a"}, true},
+		{Result{Error: Failure, Message: "a"}, Result{Error: Failure, Message: "b"}, false},
+		{failure("", "undefined variable x"), failure("", "identifier not found x"), true},
+		{failure("1\n", "division by zero"), failure("", "division by zero"), false},
+		{failure("", "division by zero"), failure("", "type mismatch: INTEGER + STRING"), false},
+		{Result{Error: Undefined, Static: true}, Result{Output: "x", Error: Undefined}, true},
+		{Result{Error: Failure}, Result{Error: ParseError}, false},
+		{Result{Error: Panic, Message: "boom"}, Result{Error: Panic, Message: "boom"}, false},
+		{Result{Error: Panic}, Result{Error: Failure}, false},
+	}
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("test %v :expected %v,got %v", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package difftest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// FuzzEngines turns the fuzzer's bytes into a well-formed program and fails
+// if the engines disagree on it. Run it with
+//
+//	go test ./difftest -run '^$' -fuzz FuzzEngines
+func FuzzEngines(f *testing.F) {
+	for _, seed := range []string{"", "\x00", "abcdef", "\x05\x01\x09\x02\x07\x03", "\xff\xfe\xfd\xfc\xfb\xfa\xf9"} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		src := generate(data)
+		onVM, onEval := Run(src), Eval(src)
+		if onVM.Error == ParseError {
+			t.Fatalf("generated program does not parse: %s\n%s", onVM.Message, src)
+		}
+		if !Equal(onVM, onEval) {
+			t.Fatalf("engines disagree on\n%s\nvm:\n%sevaluator:\n%s", src, onVM, onEval)
+		}
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	for _, data := range []string{"", "abcdef", "\xff\xfe\xfd\xfc\xfb\xfa\xf9"} {
+		src := generate([]byte(data))
+		if r := Run(src); r.Error == ParseError {
+			t.Errorf("generate(%q) does not parse: %s\n%s", data, r.Message, src)
+		}
+	}
+}
+
+// generator builds a program from a byte string: every choice consumes a
+// byte, and once the bytes run out all choices take the first option, so
+// any input gives a finite program.
+type generator struct {
+	data  []byte
+	vars  []string // values
+	funcs []string // functions, only ever called: the engines print them differently
+	inFn  int      // inside a function body, where x is bound
+	out   strings.Builder
+}
+
+func generate(data []byte) string {
+	g := &generator{data: data}
+	statements := 1 + g.choose(5)
+	for i := 0; i < statements; i++ {
+		name := fmt.Sprintf("v%d", len(g.vars)+len(g.funcs))
+		switch g.choose(3) {
+		case 0:
+			fmt.Fprintf(&g.out, "let %s = %s;\n", name, g.expression(0))
+			g.vars = append(g.vars, name)
+		case 1:
+			fmt.Fprintf(&g.out, "puts(%s);\n", g.expression(0))
+		case 2:
+			fmt.Fprintf(&g.out, "let %s = fn(x) { %s };\n", name, g.body(1))
+			g.funcs = append(g.funcs, name)
+		}
+	}
+	g.out.WriteString(g.expression(0))
+	g.out.WriteString("\n")
+	return g.out.String()
+}
+func (g *generator) choose(n int) int {
+	if len(g.data) == 0 {
+		return 0
+	}
+	b := g.data[0]
+	g.data = g.data[1:]
+	return int(b) % n
+}
+
+const maxDepth = 4
+
+func (g *generator) expression(depth int) string {
+	if depth >= maxDepth {
+		return g.atom()
+	}
+	d := depth + 1
+	switch g.choose(20) {
+	case 0, 1:
+		return g.atom()
+	case 2:
+		ops := []string{"+", "-", "*", "<", ">", "==", "!=", "<=", ">=", "&&", "||", "&", "|", "^", "<<", ">>", "**"}
+		return fmt.Sprintf("(%s %s %s)", g.expression(d), ops[g.choose(len(ops))], g.expression(d))
+	case 3:
+		return fmt.Sprintf("(%s %s %s)", g.expression(d), []string{"/", "%"}[g.choose(2)], g.expression(d))
+	case 4:
+		return fmt.Sprintf("%s%s", []string{"-", "!", "~"}[g.choose(3)], g.expression(d))
+	case 5:
+		switch g.choose(3) {
+		case 1:
+			return fmt.Sprintf("if (%s) { %s } else if (%s) { %s }", g.expression(d), g.expression(d), g.expression(d), g.expression(d))
+		case 2:
+			return fmt.Sprintf("(%s ? %s : %s)", g.expression(d), g.expression(d), g.expression(d))
+		}
+		return fmt.Sprintf("if (%s) { %s } else { %s }", g.expression(d), g.expression(d), g.expression(d))
+	case 6:
+		return fmt.Sprintf("[%s, %s][%d]", g.expression(d), g.expression(d), g.choose(5)-2)
+	case 7:
+		return fmt.Sprintf(`{"a": %s, %d: %s}[%s]`, g.expression(d), g.choose(3), g.expression(d), []string{`"a"`, "0", "1"}[g.choose(3)])
+	case 8:
+		if g.choose(2) == 0 {
+			return fmt.Sprintf("(%s |> ((x, y) => { %s })(%s))", g.expression(d), g.body(d), g.expression(d))
+		}
+		return fmt.Sprintf("fn(x, y) { %s }(%s, %s)", g.body(d), g.expression(d), g.expression(d))
+	case 9:
+		builtins := []string{"len", "first", "last", "keys", "values", "upper", "trim", "chars"}
+		return fmt.Sprintf("%s(%s)", builtins[g.choose(len(builtins))], g.expression(d))
+	case 10:
+		return fmt.Sprintf("push(%s, %s)", g.expression(d), g.expression(d))
+	case 11:
+		return fmt.Sprintf("%s[%d:%d:%d]", g.expression(d), g.choose(7)-3, g.choose(7)-3, g.choose(5)-2)
+	case 12:
+		return fmt.Sprintf(`"<${%s}>\t"`, g.expression(d))
+	case 13:
+		return fmt.Sprintf(`try { %s } catch (e) { "${e["message"]} ${e["trace"]}" }`, g.expression(d))
+	case 14:
+		return fmt.Sprintf(`try { throw %s } catch (e) { [e["value"], e["message"]] } finally { %s }`, g.expression(d), g.expression(d))
+	case 15:
+		g.inFn++
+		body := fmt.Sprintf("yield %s; for (y in %s) { yield y }", g.expression(d), g.expression(d))
+		g.inFn--
+		return fmt.Sprintf("take(fn(x) { %s }(%s), %d)", body, g.expression(d), g.choose(4))
+	case 16:
+		if g.choose(2) == 0 {
+			return fmt.Sprintf("wait_all(spawn fn(x, y) { %s }(%s, %s))[0]", g.body(d), g.expression(d), g.expression(d))
+		}
+		return fmt.Sprintf("fn(c) { send(c, %s); select { case v = recv(c) { v } default { %s } } }(channel(%d))", g.expression(d), g.expression(d), 1+g.choose(2))
+	case 17:
+		switch g.choose(4) {
+		case 2:
+			return fmt.Sprintf("fn(x, y = %s, ...r) { [x, y, r] }(%s, ...[%s])", g.expression(d), g.expression(d), g.expression(d))
+		case 3:
+			return fmt.Sprintf("fn(x, y = 1) { x + y }(y: %s, x: %s)", g.expression(d), g.expression(d))
+		}
+		if g.choose(2) == 0 {
+			return fmt.Sprintf(`fn(v) { let [a, b = %s, ..r] = v; let {"a": c = a} = {}; [c, b, r] }(%s)`,
+				g.expression(d), g.expression(d))
+		}
+		return fmt.Sprintf(`match (%s) { [a, ..r] if a => r, {"a": [a]} => a, %d => (%s), _ => (%s) }`,
+			g.expression(d), g.choose(3), g.expression(d), g.expression(d))
+	case 18:
+		switch g.choose(3) {
+		case 1:
+			return fmt.Sprintf(`(%s)?["a"]`, g.expression(d))
+		case 2:
+			return fmt.Sprintf(`{"a": %s}[%s]?.a`, g.expression(d), []string{`"a"`, `"b"`}[g.choose(2)])
+		}
+		return fmt.Sprintf("(first(%s) ?? %s)", g.expression(d), g.expression(d))
+	default:
+		if fn := g.function(); fn != "" {
+			return fmt.Sprintf("%s(%s)", fn, g.expression(d))
+		}
+		return g.atom()
+	}
+}
+func (g *generator) atom() string {
+	switch g.choose(6) {
+	case 0:
+		if g.choose(8) == 0 {
+			return "9223372036854775807"
+		}
+		return fmt.Sprint(g.choose(100))
+	case 1:
+		return []string{"true", "false"}[g.choose(2)]
+	case 2:
+		return []string{`"a"`, `"gw"`, `""`, `"é日"`}[g.choose(4)]
+	case 3:
+		return "[]"
+	default:
+		// the vm rejects unbound names even in code that never runs, so
+		// only bound ones are used
+		names := g.vars
+		if g.inFn > 0 {
+			names = append([]string{"x"}, names...)
+		}
+		if len(names) == 0 {
+			return "0"
+		}
+		return names[g.choose(len(names))]
+	}
+}
+
+func (g *generator) body(depth int) string {
+	g.inFn++
+	defer func() { g.inFn-- }()
+	if g.choose(3) == 0 {
+		deferred := []string{"puts", "len", "fn(y) { y * 2 }"}[g.choose(3)]
+		return fmt.Sprintf("defer %s(%s); %s", deferred, g.expression(depth), g.expression(depth))
+	}
+	return g.expression(depth)
+}
+
+// function returns a variable to call; any variable will do, calling a
+// non-function must fail the same way on both engines.
+func (g *generator) function() string {
+	all := append(append([]string{}, g.funcs...), g.vars...)
+	if len(all) == 0 {
+		return ""
+	}
+	return all[g.choose(len(all))]
+}
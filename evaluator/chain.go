@@ -0,0 +1,70 @@
+package evaluator
+
+import (
+	"gwine/ast"
+	"gwine/object"
+)
+
+// evalChain evaluates node, an index, slice, selector or call, along with
+// the chain of them it applies to: in a?.b["c"](d) the call applies to an
+// index, applying to an optional selector. An optional link whose left side
+// is null makes the whole chain null, which evalChain reports by returning
+// true.
+func evalChain(node ast.Node, env *object.Environment) (object.Object, bool) {
+	var left ast.Expression
+	optional := false
+	switch node := node.(type) {
+	case *ast.IndexExpression:
+		left, optional = node.Left, node.Optional
+	case *ast.SelectorExpression:
+		if mod, ok := moduleNamed(node.Left, env); ok {
+			return evalSelectorExpression(node, mod), false
+		}
+		left, optional = node.Left, node.Optional
+	case *ast.SliceExpression:
+		left = node.Left
+	case *ast.CallExpression:
+		if isQuoteCall(node, "quote", env) {
+			if len(node.Arguments) != 1 {
+				return newError("quote expects 1 argument, got %d", len(node.Arguments)), false
+			}
+			return quote(node.Arguments[0], env), false
+		}
+		left = node.Function
+	default:
+		return Eval(node, env), false
+	}
+
+	value, skipped := evalChain(left, env)
+	if skipped || optional && value == NULL {
+		return NULL, true
+	}
+	if isError(value) {
+		return value, false
+	}
+	return evalLink(node, value, env), false
+}
+
+// evalLink applies node, a link of a chain, to left, the value of the
+// links before it.
+func evalLink(node ast.Node, left object.Object, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.IndexExpression:
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.SelectorExpression:
+		return evalSelectorExpression(node, left)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, left, env)
+	default:
+		call := node.(*ast.CallExpression)
+		args := evalArgs(call.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return applyFunction(left, args, env)
+	}
+}
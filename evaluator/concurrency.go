@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"gwine/ast"
+	"gwine/object"
+)
+
+// evalSpawnExpression makes the call of node in a goroutine of its own. The
+// task shares env with the code spawning it, whose globals it sees as they
+// change; object/concurrency.go tells what tasks may count on.
+func evalSpawnExpression(node *ast.SpawnExpression, env *object.Environment) object.Object {
+	function := Eval(node.Call.Function, env)
+	if isError(function) {
+		return function
+	}
+	args := evalArgs(node.Call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	// set up before the task may race to do it
+	env.Streams()
+
+	task := object.NewTask(env.Tasks())
+	go func() {
+		result := applyFunction(function, args, env)
+		if err, ok := result.(*object.Error); ok {
+			raised(err, env)
+		}
+		task.Finish(result)
+	}()
+	return task
+}
+
+// evalSelectExpression binds the variable of the case chosen in env itself,
+// as let does.
+func evalSelectExpression(node *ast.SelectExpression, env *object.Environment) object.Object {
+	cases := make([]object.SelectCase, len(node.Cases))
+	for i, sc := range node.Cases {
+		args := evalArgs(sc.Call.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		cases[i].Channel = args[0]
+		if sc.Send() {
+			cases[i].Send = true
+			cases[i].Value = args[1]
+		}
+	}
+	index, value := object.Select(env.Tasks(), cases, node.Default != nil)
+	if index < 0 {
+		return value
+	}
+	body := node.Default
+	if index < len(node.Cases) {
+		sc := node.Cases[index]
+		if sc.Variable != nil {
+			if bound := env.Set(sc.Variable.Value, value); isError(bound) {
+				return bound
+			}
+		}
+		body = sc.Body
+	}
+	if result := Eval(body, env); result != nil {
+		return result
+	}
+	return NULL
+}
@@ -1,11 +1,18 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
 	"gwine/lexer"
+	"gwine/module"
 	"gwine/object"
 	"gwine/parser"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEva(t *testing.T) {
@@ -79,8 +86,279 @@ func TestPrefixExpressionMinus(t *testing.T) {
     env  := object.NewEnvironment()
 	obj := Eval(program,env)
 
-	obj2, _ := obj.(*object.Integer)
+	err, ok := obj.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", obj, obj)
+	}
+	if err.Message != "unknown operand: -BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", err.Message)
+	}
 
-	fmt.Println(obj2.Value)
+}
+
+// TestQuoteBound checks that quote and unquote are functions like any other
+// once a program binds them.
+func TestQuoteBound(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let quote = fn(x) { x * 2 }; quote(21)`, `42`},
+		{`let unquote = fn(x) { x }; quote(unquote(1 + 1))`, `QUOTE(unquote((1 + 1)))`},
+		{`let f = fn(quote) { quote(1) }; f(fn(x) { x + 1 })`, `2`},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		if got := Eval(program, object.NewEnvironment()).Inspect(); got != tt.expected {
+			t.Errorf("test %v : expected %v,got %v", i, tt.expected, got)
+		}
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+		{`quote(unquote(true == false))`, `false`},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		obj := Eval(program, object.NewEnvironment())
+		quote, ok := obj.(*object.Quote)
+		if !ok {
+			t.Fatalf("test %v :expected quote,got %v", i, obj)
+		}
+		if quote.Node.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, quote.Node.String())
+		}
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let infix = macro() { quote(1 + 2); }; infix();`, `(1 + 2)`},
+		{`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5);`, `((10 - 5) - (2 + 2))`},
+		{
+			`let unless = macro(cond, cons, alt) {
+				quote(if (!(unquote(cond))) { unquote(cons); } else { unquote(alt); });
+			};
+			unless(10 > 5, 1, 2);`,
+			`if(!(10 > 5)) 1else 2`,
+		},
+		{
+			`let twice = macro(x) { quote(unquote(x) + unquote(x)); };
+			let quad = macro(x) { quote(twice(twice(unquote(x)))); };
+			quad(1);`,
+			`((1 + 1) + (1 + 1))`,
+		},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("test %v :%v", i, err)
+		}
+		if expanded.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = macro(a) { quote(unquote(a)); }; m(1, 2);`, `line 1: macro m expects 1 arguments, got 2`},
+		{`let m = macro(a) { 1 }; m(1);`, `line 1: macro m must return quote(...), got INTEGER`},
+		{`let m = macro() { quote(m()); }; m();`, `line 1: macro m: expansion too deep, does it expand to itself?`},
+		{`let m = macro() { nope; }; m();`, `line 1: macro m: identifier not found nope`},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil || err.Error() != tt.expected {
+			t.Fatalf("test %v :expected error %v,got %v", i, tt.expected, err)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"util.gw": `export let double = fn(x) { x * 2 };`,
+		"lib.gw": `import "util.gw" as util;
+			export let add = fn(a, b) { a + b };
+			export let twice = fn(x) { util.double(x) };
+			let hidden = 1;`,
+		"a.gw": `import "b.gw" as b;`,
+		"b.gw": `import "a.gw" as a;`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eval := func(input string) object.Object {
+		env := object.NewEnvironment()
+		env.SetModules(object.NewModules(module.NewLoader()), filepath.Join(dir, "main.gw"))
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib.gw" as lib; import { add } from "lib.gw"; let hidden = 10; lib.twice(add(1, 2)) + hidden`, `16`},
+		{`import "lib.gw" as lib; lib.hidden`, `ERROR: module lib does not export hidden`},
+		{`import { hidden } from "lib.gw";`, `ERROR: lib.gw does not export hidden`},
+		{`import "lib.gw" as lib; lib`, `ERROR: module lib can only be used as lib.name`},
+		{`import "lib.gw" as lib; let f = fn(m) { m.add }; f(lib)`, `ERROR: module lib can only be used as lib.name`},
+		{`import "lib.gw" as lib; lib?.add(1, 2)`, `3`},
+		{`import "a.gw" as a;`, `import cycle:`},
+	}
+	for i, tt := range tests {
+		result := eval(tt.input)
+		if result == nil || !strings.Contains(result.Inspect(), tt.expected) {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, result)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetStreams(strings.NewReader("gwine\n"), &out)
+	program := parser.New(lexer.New(`
+		let greet = fn(who) { printf("hello %s!", who) };
+		greet(input("name? "));
+		puts();
+		sprintf("%d", "x")`)).ParseProgram()
+	result := Eval(program, env)
+
+	if out.String() != "name? hello gwine!\n" {
+		t.Errorf("output = %q", out.String())
+	}
+	if result.Inspect() != "ERROR: sprintf: %d expects INTEGER, got STRING" {
+		t.Errorf("result = %q", result.Inspect())
+	}
+}
+
+func TestHashes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let two = "two"; {"one": 10 - 9, two: 1 + 1, 3: 3, true: 4}`, `{one: 1, two: 2, 3: 3, true: 4}`},
+		{`{"a": 1}["a"]`, `1`},
+		{`{"a": 1}["b"]`, `null`},
+		{`let k = fn(x) { x }; {k(5): "five"}[5]`, `five`},
+		{`{[1]: 2}`, `ERROR: unusable as hash key ARRAY`},
+		{`{"a": 1}[[1]]`, `ERROR: unusable as hash key ARRAY`},
+		{`1[0]`, `ERROR: index operator not supported INTEGER`},
+		{`let h = {"b": 1, "a": 2}; keys(merge(h, {"c": 3}))`, `[b,a,c]`},
+		{`len(delete({"a": 1, "b": 2}, "a"))`, `1`},
+		{`get({}, "x", 7)`, `7`},
+	}
+	for i, tt := range tests {
+		result := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), object.NewEnvironment())
+		if result == nil || result.Inspect() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, result)
+		}
+	}
+}
+
+// TestGeneratorGoroutines checks that generators closed or dropped before
+// they are done do not leave their goroutines behind.
+func TestGeneratorGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	program := parser.New(lexer.New(`
+		let from = fn(n) { yield n; for (x in from(n + 1)) { yield x } };
+		let g = from(0);
+		let first = take(g, 10);
+		close(g);
+		[first[9], next(g, "closed"), len(take(from(0), 20))]`)).ParseProgram()
+	result := Eval(program, object.NewEnvironment())
+	if result.Inspect() != "[9,closed,20]" {
+		t.Fatalf("result = %s", result.Inspect())
+	}
+
+	// each collection finds the outermost generator still running dropped
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 200 {
+			t.Fatalf("%d generator goroutines left", runtime.NumGoroutine()-before)
+		}
+		runtime.GC()
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestSpawn is meant for the race detector too: tasks read globals and
+// write output while the top level goes on redefining them.
+func TestSpawn(t *testing.T) {
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetStreams(strings.NewReader(""), &out)
+	program := parser.New(lexer.New(`
+		let n = 0;
+		let read = fn(i) { print(i, ""); n };
+		let tasks = [];
+		for (i in [1, 2, 3, 4, 5, 6, 7, 8]) {
+			let tasks = push(tasks, spawn read(i));
+			let n = n + 1;
+		}
+		let seen = wait_all(tasks);
+		let c = channel();
+		spawn fn() { send(c, n) }();
+		[len(seen), recv(c)]`)).ParseProgram()
+	result := Eval(program, env)
+
+	// tasks may see n as it was at any time after they were spawned
+	if result.Inspect() != "[8,8]" {
+		t.Errorf("result = %s", result.Inspect())
+	}
+	if out.Len() != 16 {
+		t.Errorf("output = %q", out.String())
+	}
+}
 
+func TestConst(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const n = 5; let f = fn() { n * 2 }; f()`, `10`},
+		{`const n = 1; let f = fn() { let n = 2; n }; [f(), n]`, `[2,1]`},
+		{`let xs = []; for (i in [1, 2]) { const k = i; let xs = push(xs, k); } xs`, `[1,2]`},
+		{`let n = 1; const n = 2; n`, `2`},
+		{`const n = 1; let n = 2;`, `ERROR: cannot reassign constant n`},
+		{`const n = 1; const n = 1;`, `ERROR: cannot reassign constant n`},
+		{`const n = 1; let [a, n] = [1, 2];`, `ERROR: cannot reassign constant n`},
+		{`const n = 1; for (n in [1]) { n }`, `ERROR: cannot reassign constant n`},
+		{`const n = 1; match (2) { n => n }`, `ERROR: cannot reassign constant n`},
+		{`const n = 1; try { throw 1 } catch (n) { n }`, `ERROR: cannot reassign constant n`},
+	}
+	for i, tt := range tests {
+		result := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), object.NewEnvironment())
+		if result == nil || result.Inspect() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, result)
+		}
+	}
 }
@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"gwine/ast"
 	"gwine/object"
+	"strings"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NullObj
+	TRUE  = object.True
+	FALSE = object.False
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
@@ -28,12 +29,23 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ReturnValue{Value: val}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Const {
+			val = env.SetConst(node.Name.Value, val, node)
+		} else {
+			val = env.Set(node.Name.Value, val)
+		}
+		if isError(val) {
+			return val
+		}
+	case *ast.LetPatternStatement:
+		return evalLetPatternStatement(node, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 	case *ast.Boolean:
@@ -41,34 +53,33 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Signature: object.NewSignature(node),
+			Env: env, Body: body, Name: node.Name, Generator: node.IsGenerator()}
+	case *ast.SpreadExpression:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		return &object.Spread{Value: value}
+	case *ast.KeywordArgument:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		return &object.Keyword{Name: node.Name, Value: value}
+	case *ast.MacroLiteral:
+		return newError("macro literals must be bound by a top-level let")
 	case *ast.ArrayLiteral:
 		elements := evalArgs(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
-	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
-
-	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalArgs(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.IndexExpression, *ast.SliceExpression, *ast.SelectorExpression, *ast.CallExpression:
+		result, _ := evalChain(node, env)
+		return result
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -80,6 +91,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(left) {
 			return left
 		}
+		switch node.Operator {
+		case "&&", "||", "??":
+			if node.Operator == "&&" && !isTrue(left) || node.Operator == "||" && isTrue(left) ||
+				node.Operator == "??" && left != NULL {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
@@ -87,9 +106,60 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInflixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.ConditionalExpression:
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTrue(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		exception := object.NewException(val, env.Trace())
+		return &object.Error{Message: exception.Error(), Exception: exception}
+	case *ast.YieldExpression:
+		return evalYieldExpression(node, env)
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+	case *ast.SpawnExpression:
+		return evalSpawnExpression(node, env)
+	case *ast.SelectExpression:
+		return evalSelectExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.DeferStatement:
+		if env.TopLevel() {
+			return newError("defer is only allowed inside functions")
+		}
+		function := Eval(node.Call.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalArgs(node.Call.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		env.Defer(object.Deferred{Fn: function, Args: args})
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
-
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.ExportStatement:
+		if !env.TopLevel() {
+			return newError("export is only allowed at the top level")
+		}
+		val := Eval(node.Statement, env)
+		if isError(val) {
+			return val
+		}
+		env.Export(node.Statement.Name.Value)
 	}
 	return nil
 }
@@ -149,16 +219,21 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		if !object.IsInteger(right) {
+			return newError("unknown operand: ~%s", right.Type())
+		}
+		return object.IntegerPrefix("~", right)
 	default:
 		return newError("unknown operator: %s %s", operator, right.Type())
 	}
 }
 func evalInflixExpression(operator string, left, right object.Object) object.Object {
 	switch {
+	case object.IsInteger(left) && object.IsInteger(right):
+		return object.IntegerInfix(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInflixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInflixExpression(operator, left, right)
 	case operator == "==":
@@ -177,43 +252,34 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 	if isTrue(condition) {
 		return Eval(ie.Consequence, env)
-	} else if ie.Alternative != nil {
+	}
+	for _, ei := range ie.ElseIfs {
+		condition := Eval(ei.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTrue(condition) {
+			return Eval(ei.Consequence, env)
+		}
+	}
+	if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
-	} else {
-		return NULL
 	}
+	return NULL
 }
 func evalStringInflixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
-		return newError("string operator dismatch: %v %v %v", left.Type(), operator, right.Type())
-	}
-
 	lv := left.(*object.String).Value
 	rv := right.(*object.String).Value
-	return &object.String{Value: lv + rv}
-}
-func evalIntegerInflixExpression(operator string, left, right object.Object) object.Object {
-	leftValue, rightValue := left.(*object.Integer).Value, right.(*object.Integer).Value
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftValue + rightValue}
-	case "-":
-		return &object.Integer{Value: leftValue - rightValue}
-	case "*":
-		return &object.Integer{Value: leftValue * rightValue}
-	case "/":
-		return &object.Integer{Value: leftValue / rightValue}
-	case "<":
-		return nativeBoolToBooleanObject(leftValue < rightValue)
-	case ">":
-		return nativeBoolToBooleanObject(leftValue > rightValue)
+		return &object.String{Value: lv + rv}
 	case "==":
-		return nativeBoolToBooleanObject(leftValue == rightValue)
+		return nativeBoolToBooleanObject(lv == rv)
 	case "!=":
-		return nativeBoolToBooleanObject(leftValue != rightValue)
+		return nativeBoolToBooleanObject(lv != rv)
 	default:
-		return newError("unknow operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -230,20 +296,21 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	if !object.IsInteger(right) {
 		return newError("unknown operand: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return object.IntegerPrefix("-", right)
 
 }
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
+		if _, ok := val.(*object.Module); ok {
+			return newError("module %s can only be used as %s.name", node.Value, node.Value)
+		}
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
 		return builtin
 	}
 	return newError("identifier not found %v", node.Value)
@@ -264,39 +331,126 @@ func evalArgs(args []ast.Expression, env *object.Environment) []object.Object {
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		l := left.(*object.Array)
-		i := index.(*object.Integer).Value
+		return object.IndexArray(left.(*object.Array), index.(*object.Integer).Value)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return object.IndexString(left.(*object.String), index.(*object.Integer).Value)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left.(*object.Hash), index)
+	case left.Type() == object.EXCEPTION_OBJ:
+		return object.IndexException(left.(*object.Exception), index)
+	default:
+		return newError("index operator not supported %s", left.Type())
+	}
+}
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+	for _, part := range node.Parts {
+		value := Eval(part, env)
+		if isError(value) {
+			return value
+		}
+		out.WriteString(value.Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+func evalSliceExpression(node *ast.SliceExpression, left object.Object, env *object.Environment) object.Object {
+	bounds := []object.Object{NULL, NULL, NULL}
+	for i, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+		if bound == nil {
+			continue
+		}
+		bounds[i] = Eval(bound, env)
+		if isError(bounds[i]) {
+			return bounds[i]
+		}
+	}
+	return object.Slice(left, bounds[0], bounds[1], bounds[2])
+}
+func evalHashIndexExpression(hash *object.Hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key %s", index.Type())
+	}
+	value, ok := hash.Get(key)
+	if !ok {
+		return NULL
+	}
+	return value
+}
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
 
-		if i < 0 || i > int64(len(l.Elements)-1) {
-			return NULL
+	for _, keyNode := range node.Keys {
+		key := Eval(keyNode, env)
+		if isError(key) {
+			return key
 		}
-	
-		return l.Elements[i]
-	default:
-		return newError("array index dismatch")
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key %s", key.Type())
+		}
+		value := Eval(node.Paris[keyNode], env)
+		if isError(value) {
+			return value
+		}
+		hash.Set(hashKey, value)
 	}
+	return hash
 }
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
-		innerEnv := extendFunctionEnv(fn, args)
-		rv := Eval(fn.Body, innerEnv)
+		values, err := fn.Signature.Bind(fn.Name, args)
+		if err != nil {
+			return err
+		}
+		if fn.Generator {
+			return newGenerator(fn, values, env)
+		}
+		innerEnv := object.NewCallEnvironment(fn, env)
+		rv := runDeferred(evalBody(fn, values, innerEnv), innerEnv)
+		if err, ok := rv.(*object.Error); ok {
+			raised(err, innerEnv)
+		}
 		return unwrapReturnValue(rv)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return fn.Call(env.Streams(), env.Tasks(), args...)
 	default:
 		return newError("%v not a function", fn.Type())
 	}
 }
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-
-	env := object.NewEnclosedEnvironment(fn.Env)
-
-	for index, param := range fn.Parameters {
-		env.Set(param.Value, args[index])
+// runDeferred makes the calls deferred in env, the last one first, once its
+// function has the result. An error raised by one of them replaces the
+// result, and the calls left still run.
+func runDeferred(result object.Object, env *object.Environment) object.Object {
+	for {
+		d, ok := env.PopDeferred()
+		if !ok {
+			return result
+		}
+		if err, ok := result.(*object.Error); ok {
+			raised(err, env)
+		}
+		if rv := applyFunction(d.Fn, d.Args, env); isError(rv) {
+			result = rv
+		}
+	}
+}
+// evalBody evaluates the body of fn in env, with values from
+// object.Signature.Bind for its parameters. Those that got none get their
+// default first, which sees the parameters before it only, as in the vm.
+func evalBody(fn *object.Function, values []object.Object, env *object.Environment) object.Object {
+	for i, param := range fn.Parameters {
+		value := values[i]
+		if value == nil {
+			if value = Eval(fn.Defaults[i], env); isError(value) {
+				return value
+			}
+		}
+		env.Set(param.Value, value)
 	}
-	return env
+	return Eval(fn.Body, env)
 }
 func unwrapReturnValue(obj object.Object) object.Object {
 	if rv, ok := obj.(*object.ReturnValue); ok {
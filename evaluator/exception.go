@@ -0,0 +1,38 @@
+package evaluator
+
+import (
+	"gwine/ast"
+	"gwine/object"
+)
+
+// evalTryExpression evaluates the try block, the catch block if it raised
+// an error, and then the finally block, whose own error or return replaces
+// the result. The catch parameter is bound in env, like a let in a block.
+func evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(node.Block, env)
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		if result = env.Set(node.Param.Value, raised(err, env)); !isError(result) {
+			result = Eval(node.Catch, env)
+		}
+	}
+	if result == nil {
+		result = NULL
+	}
+	if node.Finally != nil {
+		final := Eval(node.Finally, env)
+		if isError(final) || final != nil && final.Type() == object.RETURN_VALUE_OBJ {
+			return final
+		}
+	}
+	return result
+}
+
+// raised returns the exception err raises. A runtime error gets one when it
+// first reaches a try or leaves a function, which is where it was raised, so
+// it takes the trace of env there.
+func raised(err *object.Error, env *object.Environment) *object.Exception {
+	if err.Exception == nil {
+		err.Exception = &object.Exception{Message: err.Message, Trace: env.Trace()}
+	}
+	return err.Exception
+}
@@ -0,0 +1,108 @@
+package evaluator
+
+import (
+	"gwine/ast"
+	"gwine/object"
+	"runtime"
+)
+
+// generated is what a generator's goroutine hands over: a yielded value, or
+// the end of the function with the error it raised if any.
+type generated struct {
+	value object.Object
+	ok    bool
+}
+
+// stopped unwinds the goroutine of a generator that was stopped.
+type stopped struct{}
+
+// newGenerator returns the generator made by calling fn, a function that
+// yields, with values bound by object.Signature.Bind. The function runs in a
+// goroutine of its own, started by the first Next, which hands each yielded
+// value over and waits to be resumed, so only one of them runs at a time.
+// Closing the generator, or the garbage collector finding it dropped, ends
+// the goroutine where it waits.
+func newGenerator(fn *object.Function, values []object.Object, caller *object.Environment) *object.Generator {
+	env := object.NewCallEnvironment(fn, caller)
+	resume := make(chan struct{})
+	stop := make(chan struct{})
+	results := make(chan generated)
+	env.SetYield(func(value object.Object) {
+		results <- generated{value: value, ok: true}
+		select {
+		case <-resume:
+		case <-stop:
+			panic(stopped{})
+		}
+	})
+
+	started := false
+	g := &object.Generator{Resume: func() (object.Object, bool) {
+		if started {
+			resume <- struct{}{}
+		} else {
+			started = true
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						if _, ok := r.(stopped); !ok {
+							panic(r)
+						}
+					}
+				}()
+				rv := runDeferred(evalBody(fn, values, env), env)
+				if err, ok := rv.(*object.Error); ok {
+					raised(err, env)
+					results <- generated{value: err}
+					return
+				}
+				results <- generated{}
+			}()
+		}
+		r := <-results
+		return r.value, r.ok
+	}, Stop: func() { close(stop) }}
+	// the goroutine holds on to the channels but not to g
+	runtime.SetFinalizer(g, (*object.Generator).Close)
+	return g
+}
+
+func evalYieldExpression(node *ast.YieldExpression, env *object.Environment) object.Object {
+	if env.TopLevel() {
+		return newError("yield is only allowed inside functions")
+	}
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	env.Yield(value)
+	return NULL
+}
+
+// evalForExpression binds the variable in env itself, as let does.
+func evalForExpression(node *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	it := object.Iterate(iterable)
+	if isError(it) {
+		return it
+	}
+	for {
+		value, ok := it.(*object.Iterator).Next()
+		if !ok {
+			if value != nil {
+				return value
+			}
+			return NULL
+		}
+		if bound := env.Set(node.Variable.Value, value); isError(bound) {
+			return bound
+		}
+		result := Eval(node.Body, env)
+		if result != nil && (result.Type() == object.RETURN_VALUE_OBJ || result.Type() == object.ERROR_OBJ) {
+			return result
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package evaluator
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/object"
+)
+
+// maxExpansionDepth bounds macros that expand into calls of themselves.
+const maxExpansionDepth = 100
+
+// DefineMacros moves every top-level `let name = macro(...) {...}` out of
+// program and into env.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, stmt := range program.Statements {
+		if isMacroDefinition(stmt) {
+			addMacro(stmt, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	for i := len(definitions) - 1; i >= 0; i-- {
+		index := definitions[i]
+		program.Statements = append(
+			program.Statements[:index],
+			program.Statements[index+1:]...,
+		)
+	}
+}
+func isMacroDefinition(node ast.Statement) bool {
+	let, ok := node.(*ast.LetStatement)
+	if !ok {
+		return false
+	}
+	_, ok = let.Value.(*ast.MacroLiteral)
+	return ok
+}
+func addMacro(stmt ast.Statement, env *object.Environment) {
+	let, _ := stmt.(*ast.LetStatement)
+	lit, _ := let.Value.(*ast.MacroLiteral)
+
+	macro := &object.Macro{
+		Parameters: lit.Parameters,
+		Env:        env,
+		Body:       lit.Body,
+	}
+	env.Set(let.Name.Value, macro)
+}
+
+// ExpandMacros replaces every call of a macro defined in env with the code
+// the macro returns. The arguments are passed to the macro unevaluated, as
+// quotes. program itself is left untouched.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	return expandMacros(program, env, 0)
+}
+func expandMacros(node ast.Node, env *object.Environment, depth int) (ast.Node, error) {
+	var err error
+	expanded := ast.Rewrite(node, nil, func(c *ast.Cursor) bool {
+		call, ok := c.Node().(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		macro, name, ok := isMacroCall(call, env)
+		if !ok {
+			return true
+		}
+		line := ast.StartToken(call).Line
+		if depth >= maxExpansionDepth {
+			err = fmt.Errorf("line %d: macro %s: expansion too deep, does it expand to itself?", line, name)
+			return false
+		}
+		if len(call.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf("line %d: macro %s expects %d arguments, got %d",
+				line, name, len(macro.Parameters), len(call.Arguments))
+			return false
+		}
+
+		evalEnv := extendMacroEnv(macro, quoteArgs(call))
+		evaluated := unwrapReturnValue(Eval(macro.Body, evalEnv))
+		if isError(evaluated) {
+			err = fmt.Errorf("line %d: macro %s: %s", line, name, evaluated.(*object.Error).Message)
+			return false
+		}
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			err = fmt.Errorf("line %d: macro %s must return quote(...), got %s", line, name, typeOf(evaluated))
+			return false
+		}
+
+		// the result may itself call macros
+		result, e := expandMacros(quote.Node, env, depth+1)
+		if e != nil {
+			err = e
+			return false
+		}
+		c.Replace(result)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, string, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, "", false
+	}
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, "", false
+	}
+	macro, ok := obj.(*object.Macro)
+	return macro, identifier.Value, ok
+}
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+	return args
+}
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Environment {
+	extended := object.NewEnclosedEnvironment(macro.Env)
+
+	for i, param := range macro.Parameters {
+		extended.Set(param.Value, args[i])
+	}
+	return extended
+}
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
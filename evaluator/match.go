@@ -0,0 +1,130 @@
+package evaluator
+
+import (
+	"gwine/ast"
+	"gwine/object"
+)
+
+// evalMatchExpression binds the names of the patterns it tries in env
+// itself, as let does.
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+	for _, arm := range node.Arms {
+		matched, err := matchPattern(arm.Pattern, subject, env)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, env)
+			if isError(guard) {
+				return guard
+			}
+			if !isTrue(guard) {
+				continue
+			}
+		}
+		if result := Eval(arm.Body, env); result != nil {
+			return result
+		}
+		return NULL
+	}
+	return object.NoMatch(subject)
+}
+
+// evalLetPatternStatement binds the names of the pattern in env, or is an
+// error if the value does not match it.
+func evalLetPatternStatement(node *ast.LetPatternStatement, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	matched, err := matchPattern(node.Pattern, value, env)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return object.Mismatch(node.Pattern.String(), value)
+	}
+	return nil
+}
+
+// matchPattern reports whether value matches pattern, binding its names in
+// env as it goes, in the order the vm does. It returns the error of a
+// default that raised one instead.
+func matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment) (bool, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			if bound := env.Set(pattern.Value, value); isError(bound) {
+				return false, bound
+			}
+		}
+		return true, nil
+	case *ast.LiteralPattern:
+		return object.MatchLiteral(Eval(pattern.Value, env), value), nil
+	case *ast.ArrayPattern:
+		elements := pattern.Elements
+		if !object.MatchArray(value, ast.Required(elements), len(elements), pattern.Rest != nil) {
+			return false, nil
+		}
+		values := value.(*object.Array).Elements
+		for i, e := range elements {
+			var v object.Object
+			if i < len(values) {
+				v = values[i]
+			}
+			if matched, err := matchElement(e, v, env); !matched || err != nil {
+				return false, err
+			}
+		}
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			rest := []object.Object{}
+			if len(values) > len(elements) {
+				rest = append(rest, values[len(elements):]...)
+			}
+			if bound := env.Set(pattern.Rest.Value, &object.Array{Elements: rest}); isError(bound) {
+				return false, bound
+			}
+		}
+		return true, nil
+	case *ast.HashPattern:
+		var keys []object.Object
+		for i, k := range pattern.Keys {
+			if _, ok := pattern.Values[i].(*ast.DefaultPattern); !ok {
+				keys = append(keys, Eval(k, env))
+			}
+		}
+		if !object.MatchHash(value, keys) {
+			return false, nil
+		}
+		for i, k := range pattern.Keys {
+			v, _ := value.(*object.Hash).Get(Eval(k, env).(object.Hashable))
+			if matched, err := matchElement(pattern.Values[i], v, env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// matchElement matches value, an element of an array or hash or nil if it
+// has none, against pattern, which stands for the element.
+func matchElement(pattern ast.Pattern, value object.Object, env *object.Environment) (bool, object.Object) {
+	if dflt, ok := pattern.(*ast.DefaultPattern); ok {
+		pattern = dflt.Pattern
+		if value == nil {
+			value = Eval(dflt.Default, env)
+			if isError(value) {
+				return false, value
+			}
+		}
+	}
+	return matchPattern(pattern, value, env)
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"gwine/ast"
+	"gwine/object"
+)
+
+// evalImportStatement evaluates the imported module the first time it is
+// seen, in its own top-level environment, and binds it or the selected
+// exports in env.
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	if !env.TopLevel() {
+		return newError("import is only allowed at the top level")
+	}
+	modules := env.Modules()
+	path, err := modules.Loader.Resolve(node.Path, env.File())
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	mod, ok := modules.Get(path)
+	if !ok {
+		var failed object.Object
+		if mod, failed = evalModule(path, env); failed != nil {
+			return failed
+		}
+		modules.Add(mod)
+	}
+	return bindImport(node, mod, env)
+}
+func evalModule(path string, importer *object.Environment) (*object.Module, object.Object) {
+	loader := importer.Modules().Loader
+	if err := loader.Begin(path); err != nil {
+		return nil, newError("%s", err)
+	}
+	defer loader.End(path)
+
+	program, err := loader.Load(path)
+	if err != nil {
+		return nil, newError("%s", err)
+	}
+	modEnv := object.NewModuleEnvironment(importer, path)
+	if result := Eval(program, modEnv); isError(result) {
+		return nil, newError("%s: %s", path, result.(*object.Error).Message)
+	}
+	return &object.Module{Path: path, Env: modEnv}, nil
+}
+func bindImport(node *ast.ImportStatement, mod *object.Module, env *object.Environment) object.Object {
+	if node.Alias != nil {
+		if bound := env.Set(node.Alias.Value, mod); isError(bound) {
+			return bound
+		}
+	}
+	for _, name := range node.Names {
+		val, ok := exportOf(mod, name.Value)
+		if !ok {
+			return newError("%s does not export %s", node.Path, name.Value)
+		}
+		if bound := env.Set(name.Value, val); isError(bound) {
+			return bound
+		}
+	}
+	return nil
+}
+func exportOf(mod *object.Module, name string) (object.Object, bool) {
+	if !mod.Env.Exported(name) {
+		return nil, false
+	}
+	return mod.Env.Get(name)
+}
+
+// moduleNamed returns the module expr names, if it is the name of an
+// imported module; a module is no value otherwise, as in the compiler.
+func moduleNamed(expr ast.Expression, env *object.Environment) (*object.Module, bool) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	val, _ := env.Get(ident.Value)
+	mod, ok := val.(*object.Module)
+	return mod, ok
+}
+
+// evalSelectorExpression selects an export of a module, and indexes
+// anything else with the name.
+func evalSelectorExpression(node *ast.SelectorExpression, left object.Object) object.Object {
+	mod, ok := left.(*object.Module)
+	if !ok {
+		return evalIndexExpression(left, &object.String{Value: node.Name.Value})
+	}
+	val, ok := exportOf(mod, node.Name.Value)
+	if !ok {
+		return newError("module %s does not export %s", node.Left.String(), node.Name.Value)
+	}
+	return val
+}
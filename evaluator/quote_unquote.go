@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/object"
+	"gwine/token"
+)
+
+// quote returns node unevaluated, after replacing every unquote(x) call in
+// it with the AST of x's value.
+func quote(node ast.Node, env *object.Environment) object.Object {
+	var err object.Object
+	node = ast.Rewrite(node, nil, func(c *ast.Cursor) bool {
+		if !isUnquoteCall(c.Node(), env) {
+			return true
+		}
+		call := c.Node().(*ast.CallExpression)
+		if len(call.Arguments) != 1 {
+			err = newError("unquote expects 1 argument, got %d", len(call.Arguments))
+			return false
+		}
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted
+			return false
+		}
+		replacement, ok := convertObjectToASTNode(unquoted, ast.StartToken(call))
+		if !ok {
+			err = newError("unquote: %s cannot be turned back into code", unquoted.Type())
+			return false
+		}
+		c.Replace(replacement)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+func isUnquoteCall(node ast.Node, env *object.Environment) bool {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	return isQuoteCall(call, "unquote", env)
+}
+
+// isQuoteCall reports whether call calls name, quote or unquote, while it
+// is not bound in env: a program may define functions of those names, as
+// in the compiler.
+func isQuoteCall(call *ast.CallExpression, name string, env *object.Environment) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || ident.Value != name {
+		return false
+	}
+	_, bound := env.Get(name)
+	return !bound
+}
+func convertObjectToASTNode(obj object.Object, at token.Token) (ast.Node, bool) {
+	tok := func(t token.TokenType, literal string) token.Token {
+		return token.Token{Type: t, Literal: literal, Line: at.Line, Column: at.Column}
+	}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &ast.IntegerLiteral{Token: tok(token.INT, fmt.Sprintf("%d", obj.Value)), Value: obj.Value}, true
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: tok(token.TRUE, "true"), Value: true}, true
+		}
+		return &ast.Boolean{Token: tok(token.FALSE, "false"), Value: false}, true
+	case *object.String:
+		return &ast.StringLiteral{Token: tok(token.STRING, obj.Value), Value: obj.Value}, true
+	case *object.Array:
+		array := &ast.ArrayLiteral{Token: tok(token.LBRACKET, "[")}
+		for _, el := range obj.Elements {
+			node, ok := convertObjectToASTNode(el, at)
+			if !ok {
+				return nil, false
+			}
+			exp, ok := node.(ast.Expression)
+			if !ok {
+				return nil, false
+			}
+			array.Elements = append(array.Elements, exp)
+		}
+		return array, true
+	case *object.Quote:
+		return obj.Node, true
+	default:
+		return nil, false
+	}
+}
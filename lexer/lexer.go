@@ -1,7 +1,9 @@
 package lexer
 
 import (
+	"fmt"
 	"gwine/token"
+	"strings"
 )
 
 type Lexer struct {
@@ -9,15 +11,24 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int
+	lineStart    int
+
+	comments []token.Comment
+	errors   []string
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -37,7 +48,11 @@ func (l *Lexer) peekChar() byte {
 func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
-	t := token.Token{Literal: string(l.ch)}
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.readComment()
+		l.skipWhitespace()
+	}
+	t := token.Token{Literal: string(l.ch), Line: l.line, Column: l.position - l.lineStart + 1}
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -45,16 +60,28 @@ func (l *Lexer) NextToken() token.Token {
 			t.Literal = "=="
 			l.readChar()
 		} else {
-			t.Type = token.ASSIGN
+			l.either(&t, '>', token.ARROW, token.ASSIGN)
 		}
 	case '+':
 		t.Type = token.PLUS
 	case '-':
 		t.Type = token.MINUS
 	case '*':
-		t.Type = token.ASTERISK
+		l.either(&t, '*', token.POWER, token.ASTERISK)
 	case '/':
 		t.Type = token.SLASH
+	case '%':
+		t.Type = token.PERCENT
+	case '&':
+		l.either(&t, '&', token.AND, token.AMPERSAND)
+	case '|':
+		if l.either(&t, '|', token.OR, token.PIPE); t.Type == token.PIPE {
+			l.either(&t, '>', token.PIPELINE, token.PIPE)
+		}
+	case '^':
+		t.Type = token.CARET
+	case '~':
+		t.Type = token.TILDE
 	case '!':
 		if l.peekChar() == '=' {
 			t.Type = token.NEQ
@@ -64,15 +91,29 @@ func (l *Lexer) NextToken() token.Token {
 			t.Type = token.BANG
 		}
 	case '<':
-		t.Type = token.LT
+		if l.either(&t, '<', token.SHL, token.LT); t.Type == token.LT {
+			l.either(&t, '=', token.LE, token.LT)
+		}
 	case '>':
-		t.Type = token.GT
+		if l.either(&t, '>', token.SHR, token.GT); t.Type == token.GT {
+			l.either(&t, '=', token.GE, token.GT)
+		}
 	case ',':
 		t.Type = token.COMMA
 	case ';':
 		t.Type = token.SEMICOLON
 	case ':':
 		t.Type = token.COLON
+	case '.':
+		if l.either(&t, '.', token.DOTDOT, token.DOT); t.Type == token.DOTDOT {
+			l.either(&t, '.', token.ELLIPSIS, token.DOTDOT)
+		}
+	case '?':
+		if l.either(&t, '?', token.NULLISH, token.QUESTION); t.Type == token.QUESTION {
+			if l.either(&t, '.', token.QDOT, token.QUESTION); t.Type == token.QUESTION {
+				l.either(&t, '[', token.QLBRACKET, token.QUESTION)
+			}
+		}
 	case '(':
 		t.Type = token.LPAREN
 	case ')':
@@ -86,8 +127,10 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		t.Type = token.RBRACKET
 	case '"':
+		t.Type, t.Literal = l.readString()
+	case '`':
 		t.Type = token.STRING
-		t.Literal = l.readString()
+		t.Literal = l.readRawString()
 	case 0:
 		t.Literal = ""
 		t.Type = token.EOF
@@ -95,22 +138,42 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			t.Literal = l.readIdentifier()
 			t.Type = token.LookupIdent(t.Literal)
-			return t
+			return l.end(t)
 		} else if isDigital(l.ch) {
 			t.Literal = l.readNumber()
 			t.Type = token.INT
-			return t
+			return l.end(t)
 		} else {
 			t.Type = token.ILLEGAL
 		}
 	}
 	l.readChar()
+	return l.end(t)
+
+}
+
+// end records the current position, just past the last character of t, as
+// the end of t.
+func (l *Lexer) end(t token.Token) token.Token {
+	t.EndLine = l.line
+	t.EndColumn = l.position - l.lineStart + 1
 	return t
+}
 
+// either makes t a long token, consuming next, if the character after the
+// current one is next, and a short one otherwise. The types of operators
+// are their literals.
+func (l *Lexer) either(t *token.Token, next byte, long, short token.TokenType) {
+	t.Type = short
+	if l.peekChar() == next {
+		l.readChar()
+		t.Type = long
+	}
+	t.Literal = string(t.Type)
 }
 func (l *Lexer) readIdentifier() string {
 	p := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigital(l.ch) {
 		l.readChar()
 	}
 	return l.input[p:l.position]
@@ -122,16 +185,69 @@ func (l *Lexer) readNumber() string {
 	}
 	return l.input[p:l.position]
 }
-func (l *Lexer) readString() string {
+// readString reads a double-quoted string. A string with interpolations is
+// a TEMPLATE token holding its source, for the parser to split.
+func (l *Lexer) readString() (token.TokenType, string) {
+	line := l.line
+	p := l.position + 1
+	end, parts, err := scanString(l.input, p)
+	for l.position < end {
+		l.readChar()
+	}
+	if err != nil {
+		l.errorf(line, "%s", err)
+	}
+
+	var text string
+	for _, part := range parts {
+		if part.Expr && err == nil {
+			return token.TEMPLATE, l.input[p:end]
+		}
+		if !part.Expr {
+			text += part.Text
+		}
+	}
+	return token.STRING, text
+}
+
+// readRawString reads a backtick string, which may span lines and has no
+// escapes or interpolations.
+func (l *Lexer) readRawString() string {
+	line := l.line
 	p := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.ch == '`' || l.ch == 0 {
 			break
 		}
 	}
+	if l.ch == 0 {
+		l.errorf(line, "unterminated raw string")
+	}
 	return l.input[p:l.position]
 }
+func (l *Lexer) readComment() {
+	line := l.line
+	trailing := strings.TrimSpace(l.input[l.lineStart:l.position]) != ""
+	p := l.position + 2
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	l.comments = append(l.comments, token.Comment{Line: line, Text: l.input[p:l.position], Trailing: trailing})
+}
+
+// Comments returns the comments skipped so far.
+func (l *Lexer) Comments() []token.Comment {
+	return l.comments
+}
+
+// Errors returns the malformed literals found so far, as "line: message".
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+func (l *Lexer) errorf(line int, format string, a ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf("%d: ", line)+fmt.Sprintf(format, a...))
+}
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\n' || l.ch == '\t' || l.ch == '\r' {
 		l.readChar()
@@ -7,7 +7,7 @@ import (
 
 func TestNextToken(t *testing.T) {
 	input := `=+(){},;
-	let`
+	let v2 9`
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -22,6 +22,8 @@ func TestNextToken(t *testing.T) {
 		{token.COMMA, ","},
 		{token.SEMICOLON, ";"},
 		{token.LET,"let"},
+		{token.IDENT, "v2"},
+		{token.INT, "9"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -109,3 +111,141 @@ func TestStructToken(t *testing.T) {
 		}
 	}
 }
+
+func TestComments(t *testing.T) {
+	input := `let a = 1; // first
+	// second
+	a`
+
+	l := New(input)
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 3, 2},
+		{token.EOF, 3, 3},
+	}
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("test %v :expected %v at %v:%v,got %+v", i, tt.expectedType, tt.expectedLine, tt.expectedColumn, tok)
+		}
+	}
+	comments := l.Comments()
+	if len(comments) != 2 || comments[0].Text != " first" || comments[1].Line != 2 {
+		t.Fatalf("comments wrong,got %+v", comments)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"a\tb\n\"c\"\\"`, token.STRING, "a\tb\n\"c\"\\"},
+		{`"\u{65e5}\u{1F600}\$"`, token.STRING, "日😀$"},
+		{`"cost: $5"`, token.STRING, "cost: $5"},
+		{"`raw \\n\n${x}`", token.STRING, "raw \\n\n${x}"},
+		{`"hi ${name}!"`, token.TEMPLATE, "hi ${name}!"},
+		{`"${join(a, "}")}"`, token.TEMPLATE, `${join(a, "}")}`},
+	}
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test %v :expected %v %q,got %v %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if len(l.Errors()) != 0 {
+			t.Fatalf("test %v :unexpected errors %v", i, l.Errors())
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("test %v :expected EOF after the string,got %v", i, next.Type)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\qb"`, `1: invalid escape sequence \q`},
+		{"\n\"abc", "2: unterminated string"},
+		{"`abc", "1: unterminated raw string"},
+		{`"\u{110000}"`, `1: invalid unicode escape \u{110000}`},
+		{`"\u65"`, `1: invalid unicode escape, want \u{X} with 1 to 6 hex digits`},
+		{`"${x`, "1: unterminated ${ in string"},
+	}
+	for i, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0] != tt.expected {
+			t.Errorf("test %v :expected [%s],got %v", i, tt.expected, l.Errors())
+		}
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	parts, err := SplitTemplate(`a\t${x + 1}${"}"}b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TemplatePart{{Text: "a\t"}, {Text: "x + 1", Expr: true}, {Text: `"}"`, Expr: true}, {Text: "b"}}
+	if len(parts) != len(expected) {
+		t.Fatalf("expected %v,got %v", expected, parts)
+	}
+	for i := range parts {
+		if parts[i] != expected[i] {
+			t.Errorf("part %v :expected %v,got %v", i, expected[i], parts[i])
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `% ** * <= < << >= > >> && & || |> | ^ ~ => = == ... .. . ?? ?. ?[ ?`
+	expected := []token.TokenType{token.PERCENT, token.POWER, token.ASTERISK, token.LE, token.LT, token.SHL,
+		token.GE, token.GT, token.SHR, token.AND, token.AMPERSAND, token.OR, token.PIPELINE, token.PIPE, token.CARET, token.TILDE,
+		token.ARROW, token.ASSIGN, token.EQ, token.ELLIPSIS, token.DOTDOT, token.DOT,
+		token.NULLISH, token.QDOT, token.QLBRACKET, token.QUESTION, token.EOF}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("test %v :expected %v,got %v", i, tt, tok.Type)
+		}
+		if tt != token.EOF && tok.Literal != string(tt) {
+			t.Fatalf("test %v :expected literal %v,got %v", i, tt, tok.Literal)
+		}
+	}
+}
+
+func TestTokenEnd(t *testing.T) {
+	input := "abc \"a\\tb\" `x\ny` <=\n"
+
+	l := New(input)
+	tests := []struct {
+		expectedType      token.TokenType
+		expectedEndLine   int
+		expectedEndColumn int
+	}{
+		{token.IDENT, 1, 4},
+		{token.STRING, 1, 11},
+		{token.STRING, 2, 3},
+		{token.LE, 2, 6},
+	}
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.EndLine != tt.expectedEndLine || tok.EndColumn != tt.expectedEndColumn {
+			t.Fatalf("test %v :expected %v ending at %v:%v,got %+v", i, tt.expectedType, tt.expectedEndLine, tt.expectedEndColumn, tok)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TemplatePart is a piece of an interpolated string: text with its escapes
+// decoded, or the source of an embedded ${...} expression.
+type TemplatePart struct {
+	Text string
+	Expr bool
+}
+
+// SplitTemplate splits the body of an interpolated string, the literal of a
+// token.TEMPLATE, into its parts.
+func SplitTemplate(body string) ([]TemplatePart, error) {
+	end, parts, err := scanString(body+`"`, 0)
+	if err == nil && end != len(body) {
+		err = fmt.Errorf("unexpected end of string")
+	}
+	return parts, err
+}
+
+// scanString scans a double-quoted string whose body starts at input[i]. It
+// returns the index of the closing quote, or len(input) if there is none,
+// and the parts of the string. After an error it still finds the end, so
+// that lexing can go on.
+func scanString(input string, i int) (int, []TemplatePart, error) {
+	var (
+		parts    []TemplatePart
+		text     strings.Builder
+		firstErr error
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for i < len(input) {
+		switch {
+		case input[i] == '"':
+			if text.Len() > 0 || len(parts) == 0 {
+				parts = append(parts, TemplatePart{Text: text.String()})
+			}
+			return i, parts, firstErr
+		case input[i] == '\\':
+			decoded, n, err := unescape(input[i:])
+			if err != nil {
+				fail(err)
+			}
+			text.WriteString(decoded)
+			i += n
+		case strings.HasPrefix(input[i:], "${"):
+			end, err := skipExpression(input, i+2)
+			if err != nil {
+				fail(err)
+			}
+			if text.Len() > 0 {
+				parts = append(parts, TemplatePart{Text: text.String()})
+				text.Reset()
+			}
+			parts = append(parts, TemplatePart{Text: input[i+2 : end], Expr: true})
+			i = end + 1
+		default:
+			text.WriteByte(input[i])
+			i++
+		}
+	}
+	fail(fmt.Errorf("unterminated string"))
+	return len(input), parts, firstErr
+}
+
+// skipExpression returns the index of the '}' closing the expression that
+// starts at input[i], skipping braces and strings nested in it.
+func skipExpression(input string, i int) (int, error) {
+	depth := 1
+	for i < len(input) {
+		switch input[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		case '"':
+			end, _, err := scanString(input, i+1)
+			if err != nil {
+				return len(input), err
+			}
+			i = end
+		case '`':
+			end := strings.IndexByte(input[i+1:], '`')
+			if end < 0 {
+				return len(input), fmt.Errorf("unterminated raw string")
+			}
+			i += end + 1
+		}
+		i++
+	}
+	return len(input), fmt.Errorf("unterminated ${ in string")
+}
+
+// unescape decodes the escape sequence at the start of s and returns it with
+// the number of bytes it takes. The escapes are \n \t \r \0 \\ \" \$ and
+// \u{X}, where X is 1 to 6 hex digits of a code point.
+func unescape(s string) (string, int, error) {
+	if len(s) < 2 {
+		return "", len(s), fmt.Errorf("unterminated string")
+	}
+	switch s[1] {
+	case 'n':
+		return "\n", 2, nil
+	case 't':
+		return "\t", 2, nil
+	case 'r':
+		return "\r", 2, nil
+	case '0':
+		return "\x00", 2, nil
+	case '\\', '"', '$':
+		return s[1:2], 2, nil
+	case 'u':
+		end := strings.IndexByte(s, '}')
+		if !strings.HasPrefix(s[2:], "{") || end < 0 || end > 9 {
+			return "", 2, fmt.Errorf(`invalid unicode escape, want \u{X} with 1 to 6 hex digits`)
+		}
+		code, err := strconv.ParseUint(s[3:end], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return "", end + 1, fmt.Errorf("invalid unicode escape %s", s[:end+1])
+		}
+		return string(rune(code)), end + 1, nil
+	}
+	r, size := utf8.DecodeRuneInString(s[1:])
+	return "", 1 + size, fmt.Errorf("invalid escape sequence \\%c", r)
+}
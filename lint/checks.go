@@ -0,0 +1,298 @@
+package lint
+
+import (
+	"gwine/ast"
+	"gwine/compiler"
+	"gwine/object"
+	"strconv"
+	"strings"
+)
+
+var Checks = []*Check{
+	{
+		Name: "unused",
+		Doc:  "let bindings and parameters inside functions that are never read; top-level lets are left alone",
+		Run:  checkUnused,
+	},
+	{
+		Name: "shadow",
+		Doc:  "bindings that hide a builtin function such as len or first",
+		Run:  checkShadow,
+	},
+	{
+		Name: "unreachable",
+		Doc:  "statements following a return in the same block",
+		Run:  checkUnreachable,
+	},
+	{
+		Name: "arity",
+		Doc:  "calls to a known function with the wrong number of arguments",
+		Run:  checkArity,
+	},
+	{
+		Name: "constcmp",
+		Doc:  "comparisons whose result does not depend on any variable",
+		Run:  checkConstCompare,
+	},
+	{
+		Name: "ifvalue",
+		Doc:  "if without else used as a value, which yields null when the condition is false",
+		Run:  checkIfValue,
+	},
+	{
+		Name: "dupkey",
+		Doc:  "hash literals with the same literal key more than once",
+		Run:  checkDuplicateKey,
+	},
+}
+
+func checkUnused(pass *Pass) {
+	for _, b := range pass.Bindings {
+		if b.Uses > 0 || b.Global || strings.HasPrefix(b.Name, "_") {
+			continue
+		}
+		switch b.Kind {
+		case LetBinding, FuncBinding, MatchBinding:
+			pass.Reportf(b.Token, "%s declared but not used", b.Name)
+		case ParamBinding:
+			pass.Reportf(b.Token, "parameter %s is never used", b.Name)
+		}
+	}
+}
+
+func checkShadow(pass *Pass) {
+	builtins := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		builtins.DefineBuiltin(i, v.Name)
+	}
+	for _, b := range pass.Bindings {
+		if b.Kind == FieldBinding {
+			continue
+		}
+		if sym, ok := builtins.Resolve(b.Name); ok && sym.Scope == compiler.BuiltinScope {
+			pass.Reportf(b.Token, "%s shadows the builtin function %s", b.Name, sym.Name)
+		}
+	}
+}
+
+func checkUnreachable(pass *Pass) {
+	report := func(stmts []ast.Statement) {
+		for i, s := range stmts {
+			if _, ok := s.(*ast.ReturnStatement); ok && i+1 < len(stmts) {
+				pass.Reportf(ast.StartToken(stmts[i+1]), "unreachable code after return")
+				return
+			}
+		}
+	}
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			report(node.Statements)
+		case *ast.BlockStatement:
+			report(node.Statements)
+		}
+		return true
+	})
+}
+
+func checkArity(pass *Pass) {
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		b := pass.Uses[ident]
+		if b == nil {
+			return true
+		}
+		fn, ok := b.Value.(*ast.FunctionLiteral)
+		if !ok || fn == nil {
+			return true
+		}
+		max := len(fn.Parameters)
+		min := 0
+		for i := range fn.Parameters {
+			if fn.Default(i) == nil {
+				min++
+			}
+		}
+		if fn.Rest {
+			min--
+			max = -1
+		}
+		for _, arg := range call.Arguments {
+			if _, ok := arg.(*ast.SpreadExpression); ok {
+				// the number of arguments is only known when it runs
+				return true
+			}
+		}
+		n := len(call.Arguments)
+		switch {
+		case min == max && n != min:
+			pass.Reportf(ident.Token, "%s expects %d arguments, got %d", ident.Value, min, n)
+		case max < 0 && n < min:
+			pass.Reportf(ident.Token, "%s expects at least %d arguments, got %d", ident.Value, min, n)
+		case max >= 0 && (n < min || n > max):
+			pass.Reportf(ident.Token, "%s expects %d to %d arguments, got %d", ident.Value, min, max, n)
+		}
+		return true
+	})
+}
+
+func checkConstCompare(pass *Pass) {
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		ie, ok := node.(*ast.InfixExpression)
+		if !ok {
+			return true
+		}
+		switch ie.Operator {
+		case "==", "!=", "<", ">", "<=", ">=":
+		default:
+			return true
+		}
+		if result, ok := constCompare(pass, ie); ok {
+			pass.Reportf(ast.StartToken(ie), "comparison %s is always %t", ie.String(), result)
+		}
+		return true
+	})
+}
+
+// constCompare folds comparisons between two literals or between a variable
+// and itself.
+func constCompare(pass *Pass, ie *ast.InfixExpression) (bool, bool) {
+	switch l := ie.Left.(type) {
+	case *ast.Identifier:
+		r, ok := ie.Right.(*ast.Identifier)
+		if !ok || l.Value != r.Value || pass.Uses[l] != pass.Uses[r] {
+			return false, false
+		}
+		return ie.Operator == "==" || ie.Operator == "<=" || ie.Operator == ">=", true
+	case *ast.IntegerLiteral:
+		r, ok := ie.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return false, false
+		}
+		switch ie.Operator {
+		case "==":
+			return l.Value == r.Value, true
+		case "!=":
+			return l.Value != r.Value, true
+		case "<":
+			return l.Value < r.Value, true
+		case ">":
+			return l.Value > r.Value, true
+		case "<=":
+			return l.Value <= r.Value, true
+		case ">=":
+			return l.Value >= r.Value, true
+		}
+	case *ast.Boolean:
+		r, ok := ie.Right.(*ast.Boolean)
+		if !ok {
+			return false, false
+		}
+		switch ie.Operator {
+		case "==":
+			return l.Value == r.Value, true
+		case "!=":
+			return l.Value != r.Value, true
+		}
+	case *ast.StringLiteral:
+		r, ok := ie.Right.(*ast.StringLiteral)
+		if !ok {
+			return false, false
+		}
+		switch ie.Operator {
+		case "==":
+			return l.Value == r.Value, true
+		case "!=":
+			return l.Value != r.Value, true
+		}
+	}
+	return false, false
+}
+
+func checkIfValue(pass *Pass) {
+	report := func(exps ...ast.Expression) {
+		for _, e := range exps {
+			if ie, ok := e.(*ast.IfExpression); ok && ie.Alternative == nil {
+				pass.Reportf(ie.Token, "if without else used as a value")
+			}
+		}
+	}
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			report(node.Value)
+		case *ast.LetPatternStatement:
+			report(node.Value)
+		case *ast.DefaultPattern:
+			report(node.Default)
+		case *ast.ReturnStatement:
+			report(node.ReturnValue)
+		case *ast.ThrowStatement:
+			report(node.Value)
+		case *ast.YieldExpression:
+			report(node.Value)
+		case *ast.ForExpression:
+			report(node.Iterable)
+		case *ast.MatchExpression:
+			report(node.Subject)
+		case *ast.MatchArm:
+			report(node.Guard)
+		case *ast.ConditionalExpression:
+			report(node.Condition, node.Consequence, node.Alternative)
+		case *ast.PrefixExpression:
+			report(node.Right)
+		case *ast.InfixExpression:
+			report(node.Left, node.Right)
+		case *ast.CallExpression:
+			report(node.Arguments...)
+		case *ast.ArrayLiteral:
+			report(node.Elements...)
+		case *ast.InterpolatedString:
+			report(node.Parts...)
+		case *ast.IndexExpression:
+			report(node.Left, node.Index)
+		case *ast.SliceExpression:
+			report(node.Left, node.Start, node.End, node.Step)
+		case *ast.HashLiteral:
+			for _, k := range node.Keys {
+				report(k, node.Paris[k])
+			}
+		}
+		return true
+	})
+}
+
+func checkDuplicateKey(pass *Pass) {
+	ast.Inspect(pass.Program, func(node ast.Node) bool {
+		hl, ok := node.(*ast.HashLiteral)
+		if !ok {
+			return true
+		}
+		seen := make(map[string]bool)
+		for _, k := range hl.Keys {
+			var key string
+			switch k := k.(type) {
+			case *ast.IntegerLiteral:
+				key = "int:" + strconv.FormatInt(k.Value, 10)
+			case *ast.StringLiteral:
+				key = "string:" + k.Value
+			case *ast.Boolean:
+				key = "bool:" + k.String()
+			default:
+				continue
+			}
+			if seen[key] {
+				pass.Reportf(ast.StartToken(k), "duplicate key %s in hash literal", k.String())
+			}
+			seen[key] = true
+		}
+		return true
+	})
+}
@@ -0,0 +1,175 @@
+// Package lint reports suspicious constructs in gwine programs.
+//
+// A diagnostic can be silenced with a `// lint:ignore` comment on the same
+// line, or on a line of its own above it, optionally followed by the names
+// of the checks to silence:
+//
+//	let unused = 1; // lint:ignore unused
+package lint
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/lexer"
+	"gwine/parser"
+	"gwine/token"
+	"sort"
+	"strings"
+)
+
+const ignoreDirective = "lint:ignore"
+
+type Diagnostic struct {
+	Check   string
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d: %s (%s)", d.Line, d.Message, d.Check)
+}
+
+// Check is a single lint rule. Run inspects pass.Program and reports what it
+// finds through pass.Reportf.
+type Check struct {
+	Name string
+	Doc  string
+	Run  func(pass *Pass)
+}
+
+// Pass is the state handed to a check: the program and the result of name
+// resolution over it.
+type Pass struct {
+	Program  *ast.Program
+	Bindings []*Binding
+	Uses     map[*ast.Identifier]*Binding
+
+	check *Check
+	diags []Diagnostic
+}
+
+func (p *Pass) Reportf(tok token.Token, format string, a ...interface{}) {
+	p.diags = append(p.diags, Diagnostic{
+		Check:   p.check.Name,
+		Line:    tok.Line,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+type Linter struct {
+	checks   []*Check
+	disabled map[string]bool
+}
+
+// New returns a linter with every check in Checks enabled.
+func New() *Linter {
+	l := &Linter{disabled: make(map[string]bool)}
+	for _, c := range Checks {
+		l.Register(c)
+	}
+	return l
+}
+func (l *Linter) Register(c *Check) {
+	l.checks = append(l.checks, c)
+}
+func (l *Linter) Checks() []*Check {
+	return l.checks
+}
+func (l *Linter) Enable(name string) error {
+	if l.lookup(name) == nil {
+		return fmt.Errorf("unknown check %s", name)
+	}
+	delete(l.disabled, name)
+	return nil
+}
+func (l *Linter) Disable(name string) error {
+	if l.lookup(name) == nil {
+		return fmt.Errorf("unknown check %s", name)
+	}
+	l.disabled[name] = true
+	return nil
+}
+func (l *Linter) Enabled(name string) bool {
+	return l.lookup(name) != nil && !l.disabled[name]
+}
+func (l *Linter) lookup(name string) *Check {
+	for _, c := range l.checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Lint parses input and runs every enabled check over it. Programs that do
+// not parse are not linted; the parser errors are returned instead.
+func (l *Linter) Lint(input string) ([]Diagnostic, error) {
+	lx := lexer.New(input)
+	p := parser.New(lx)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse error: %s", strings.Join(p.Errors(), "; "))
+	}
+	return l.LintProgram(program, lx.Comments()), nil
+}
+
+// LintProgram runs every enabled check over an already parsed program.
+// comments are used to honour lint:ignore directives and may be nil.
+func (l *Linter) LintProgram(program *ast.Program, comments []token.Comment) []Diagnostic {
+	r := resolve(program)
+	ignored := ignoredLines(comments)
+
+	diags := []Diagnostic{}
+	for _, c := range l.checks {
+		if l.disabled[c.Name] {
+			continue
+		}
+		pass := &Pass{Program: program, Bindings: r.bindings, Uses: r.uses, check: c}
+		c.Run(pass)
+		for _, d := range pass.diags {
+			if !ignored.covers(d) {
+				diags = append(diags, d)
+			}
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Line < diags[j].Line
+	})
+	return diags
+}
+
+// suppressions maps a line to the checks silenced on it; the empty name
+// silences every check.
+type suppressions map[int]map[string]bool
+
+func ignoredLines(comments []token.Comment) suppressions {
+	s := suppressions{}
+	for _, c := range comments {
+		text := strings.TrimSpace(c.Text)
+		if !strings.HasPrefix(text, ignoreDirective) {
+			continue
+		}
+		names := strings.FieldsFunc(text[len(ignoreDirective):], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		lines := []int{c.Line}
+		if !c.Trailing {
+			lines = append(lines, c.Line+1)
+		}
+		for _, line := range lines {
+			if s[line] == nil {
+				s[line] = make(map[string]bool)
+			}
+			for _, n := range names {
+				s[line][n] = true
+			}
+		}
+	}
+	return s
+}
+func (s suppressions) covers(d Diagnostic) bool {
+	return s[d.Line][""] || s[d.Line][d.Check]
+}
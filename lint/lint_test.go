@@ -0,0 +1,102 @@
+package lint
+
+import (
+	"testing"
+)
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let f = fn(x){ let y = 1; return x; }; f(1);`, []string{"unused"}},
+		{`let f = fn(x, y){ return x; }; f(1, 2);`, []string{"unused"}},
+		{`let f = fn(_x){ return 1; }; f(1);`, []string{}},
+		{`let len = 1;`, []string{"shadow"}},
+		{`let f = fn(first){ return first; }; f(1);`, []string{"shadow"}},
+		{`let f = fn(){ return 1; 2; }; f();`, []string{"unreachable"}},
+		{`let f = fn(a, b){ return a + b; }; f(1);`, []string{"arity"}},
+		{`fn g(a){ return a; } g(1, 2);`, []string{"arity"}},
+		{`let f = fn(a, b = 1, ...c){ [a, b, c] }; f(1); f(1, 2, 3, 4); f(b: 2, a: 1); f(...[1]);`, []string{}},
+		{`let f = fn(a, b = 1){ a + b }; f(1, 2, 3);`, []string{"arity"}},
+		{`let f = fn(a, ...b){ [a, b] }; f();`, []string{"arity"}},
+		{`let f = fn(a, b = a){ a + b }; f(1);`, []string{}},
+		{`let f = (a, b) => a + b; 1 |> f(2);`, []string{}},
+		{`let f = a => a; 1 |> f(2);`, []string{"arity"}},
+		{`let f = fn(xs){ xs |> (x => 1)() }; f(1);`, []string{"unused"}},
+		{`1 == 1;`, []string{"constcmp"}},
+		{`let a = 1; a != a;`, []string{"constcmp"}},
+		{`"a" == "b";`, []string{"constcmp"}},
+		{`let a = 1; let b = 2; a < b;`, []string{}},
+		{`2 >= 3;`, []string{"constcmp"}},
+		{`let a = 1; a <= a;`, []string{"constcmp"}},
+		{`let a = if (true) { 1 };`, []string{"ifvalue"}},
+		{`let a = if (true) { 1 } else { 2 };`, []string{}},
+		{`let a = if (true) { 1 } else if (false) { 2 };`, []string{"ifvalue"}},
+		{`let a = if (true) { 1 } else if (false) { 2 } else { 3 };`, []string{}},
+		{`let f = fn(x){ x ? if (x) { 1 } : 2 }; f(1);`, []string{"ifvalue"}},
+		{`let f = fn(x, h){ x > 0 ? h?.k : h?["j"] ?? x }; f(1, {});`, []string{}},
+		{`let h = {"a": 1, "b": 2, "a": 3};`, []string{"dupkey"}},
+		{`let h = {1: 1, 2: 2};`, []string{}},
+		{`let f = fn(xs){ for (x in xs) { yield x; } }; f([1]);`, []string{}},
+		{`let f = fn(x){ yield if (x) { 1 }; }; f(1);`, []string{"ifvalue"}},
+		{`let f = fn(v){ match (v) { [a, ..rest] if a > 0 => rest, {"k": k} => k, _ => 0 } }; f([1]);`, []string{}},
+		{`let f = fn(v){ match (v) { [a, b] => a, _ => 0 } }; f([1]);`, []string{"unused"}},
+		{`let f = fn(v){ match (v) { x if if (x) { 1 } => x } }; f(1);`, []string{"ifvalue"}},
+		{`let f = fn(v){ let [a, b = a, ..rest] = v; [b, rest] }; f([1]);`, []string{}},
+		{`let f = fn(v){ let {a, b} = v; a }; f({});`, []string{"unused"}},
+		{`let f = fn(v){ let [a = if (v) { 1 }] = v; a }; f([]);`, []string{"ifvalue"}},
+	}
+
+	for i, tt := range tests {
+		diags, err := New().Lint(tt.input)
+		if err != nil {
+			t.Fatalf("test %v : %v", i, err)
+		}
+		if len(diags) != len(tt.expected) {
+			t.Fatalf("test %v : expected %v diagnostics,got %v", i, tt.expected, diags)
+		}
+		for j, d := range diags {
+			if d.Check != tt.expected[j] {
+				t.Fatalf("test %v : expected check %v,got %v", i, tt.expected[j], d)
+			}
+		}
+	}
+}
+
+func TestIgnoreAndDisable(t *testing.T) {
+	input := `
+	let len = 1; // lint:ignore shadow
+	// lint:ignore
+	let first = 2;
+	let last = 3; // lint:ignore unused
+	1 == 1;
+	`
+
+	diags, err := New().Lint(input)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(diags) != 2 || diags[0].Line != 5 || diags[1].Check != "constcmp" {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+
+	l := New()
+	if err := l.Disable("shadow"); err != nil {
+		t.Fatalf("%v", err)
+	}
+	diags, _ = l.Lint(input)
+	if len(diags) != 1 || diags[0].Check != "constcmp" {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+
+	if err := l.Disable("nope"); err == nil {
+		t.Fatalf("expected error for unknown check")
+	}
+
+	// a trailing directive leaves the next line alone
+	diags, _ = New().Lint("let len = 1; // lint:ignore shadow\nlet first = 2; first;")
+	if len(diags) != 1 || diags[0].Line != 2 || diags[0].Check != "shadow" {
+		t.Fatalf("unexpected diagnostics %v", diags)
+	}
+}
@@ -0,0 +1,197 @@
+package lint
+
+import (
+	"gwine/ast"
+	"gwine/token"
+)
+
+type BindingKind int
+
+const (
+	LetBinding BindingKind = iota
+	ParamBinding
+	FuncBinding
+	FieldBinding
+	TypeBinding
+	ImportBinding
+	CatchBinding
+	LoopBinding
+	SelectBinding
+	MatchBinding
+)
+
+// Binding is a name introduced by let, a parameter list, a function or
+// struct declaration, a struct field, an import, a catch, a for loop, a
+// case of a select or the pattern of a match arm.
+type Binding struct {
+	Name   string
+	Kind   BindingKind
+	Token  token.Token
+	Value  ast.Expression // bound value for let and fn declarations
+	Global bool
+	Uses   int
+}
+
+type scope struct {
+	names map[string]*Binding
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]*Binding), outer: outer}
+}
+func (s *scope) lookup(name string) *Binding {
+	if b, ok := s.names[name]; ok {
+		return b
+	}
+	if s.outer != nil {
+		return s.outer.lookup(name)
+	}
+	return nil
+}
+
+// resolver binds every identifier use to its declaration. Like the compiler,
+// only functions open a new scope; blocks share the scope of their function.
+type resolver struct {
+	scope    *scope
+	bindings []*Binding
+	uses     map[*ast.Identifier]*Binding
+}
+
+func resolve(program *ast.Program) *resolver {
+	r := &resolver{
+		scope: newScope(nil),
+		uses:  make(map[*ast.Identifier]*Binding),
+	}
+	ast.Inspect(program, r.visit)
+	return r
+}
+func (r *resolver) define(kind BindingKind, tok token.Token, name string, value ast.Expression) {
+	b := &Binding{
+		Name:   name,
+		Kind:   kind,
+		Token:  tok,
+		Value:  value,
+		Global: r.scope.outer == nil,
+	}
+	r.bindings = append(r.bindings, b)
+	r.scope.names[name] = b
+}
+func (r *resolver) visit(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		r.define(LetBinding, node.Name.Token, node.Name.Value, node.Value)
+		if node.Value != nil {
+			ast.Inspect(node.Value, r.visit)
+		}
+		return false
+	case *ast.LetPatternStatement:
+		if node.Value != nil {
+			ast.Inspect(node.Value, r.visit)
+		}
+		r.pattern(LetBinding, node.Pattern)
+		return false
+	case *ast.FunctionDeclarionStatement:
+		r.define(FuncBinding, node.Token, node.Name, node.Body)
+		if node.Body != nil {
+			r.function(node.Body.Parameters, node.Body.Defaults, node.Body.Body, nil)
+		}
+		return false
+	case *ast.FunctionLiteral:
+		r.function(node.Parameters, node.Defaults, node.Body, nil)
+		return false
+	case *ast.MacroLiteral:
+		r.function(node.Parameters, nil, node.Body, nil)
+		return false
+	case *ast.StructDeclarion:
+		r.define(TypeBinding, node.Token, node.Name, nil)
+		for _, m := range node.Methods {
+			r.function(m.Parameters, m.Defaults, m.Body, node.Vars)
+		}
+		return false
+	case *ast.ImportStatement:
+		if node.Alias != nil {
+			r.define(ImportBinding, node.Alias.Token, node.Alias.Value, nil)
+		}
+		for _, name := range node.Names {
+			r.define(ImportBinding, name.Token, name.Value, nil)
+		}
+		return false
+	case *ast.TryExpression:
+		ast.Inspect(node.Block, r.visit)
+		if node.Catch != nil {
+			r.define(CatchBinding, node.Param.Token, node.Param.Value, nil)
+			ast.Inspect(node.Catch, r.visit)
+		}
+		if node.Finally != nil {
+			ast.Inspect(node.Finally, r.visit)
+		}
+		return false
+	case *ast.ForExpression:
+		ast.Inspect(node.Iterable, r.visit)
+		r.define(LoopBinding, node.Variable.Token, node.Variable.Value, nil)
+		ast.Inspect(node.Body, r.visit)
+		return false
+	case *ast.SelectCase:
+		ast.Inspect(node.Call, r.visit)
+		if node.Variable != nil {
+			r.define(SelectBinding, node.Variable.Token, node.Variable.Value, nil)
+		}
+		ast.Inspect(node.Body, r.visit)
+		return false
+	case *ast.MatchArm:
+		r.pattern(MatchBinding, node.Pattern)
+		if node.Guard != nil {
+			ast.Inspect(node.Guard, r.visit)
+		}
+		ast.Inspect(node.Body, r.visit)
+		return false
+	case *ast.SelectorExpression:
+		// the selected name belongs to the module, not to this scope
+		ast.Inspect(node.Left, r.visit)
+		return false
+	case *ast.Identifier:
+		if b := r.scope.lookup(node.Value); b != nil {
+			b.Uses++
+			r.uses[node] = b
+		}
+	}
+	return true
+}
+
+// pattern defines the names pattern binds, resolving the defaults in it as
+// it goes, since a default may use the names bound before it.
+func (r *resolver) pattern(kind BindingKind, pattern ast.Pattern) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.DefaultPattern:
+			ast.Inspect(node.Default, r.visit)
+			r.pattern(kind, node.Pattern)
+			return false
+		case *ast.Identifier:
+			if node.Value != "_" {
+				r.define(kind, node.Token, node.Value, nil)
+			}
+		}
+		return true
+	})
+}
+
+// function resolves a function; defaults are those of its parameters, as
+// in ast.FunctionLiteral, which see the parameters before them.
+func (r *resolver) function(params []*ast.Identifier, defaults []ast.Expression, body *ast.BlockStatement, fields []*ast.Identifier) {
+	r.scope = newScope(r.scope)
+	for _, f := range fields {
+		r.define(FieldBinding, f.Token, f.Value, nil)
+	}
+	for i, p := range params {
+		if i < len(defaults) && defaults[i] != nil {
+			ast.Inspect(defaults[i], r.visit)
+		}
+		r.define(ParamBinding, p.Token, p.Value, nil)
+	}
+	if body != nil {
+		ast.Inspect(body, r.visit)
+	}
+	r.scope = r.scope.outer
+}
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"gwine/ast"
+	"gwine/lexer"
+	"gwine/lint"
+	"gwine/parser"
 	"gwine/repl"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func main() {
@@ -10,6 +19,159 @@ func main() {
 	// if err != nil {
 	// 	fmt.Errorf("kksk")
 	// }
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "parse":
+			os.Exit(runParse(os.Args[2:]))
+		case "run":
+			os.Exit(runFile(os.Args[2:]))
+		}
+	}
 	repl.StartForVm(os.Stdin, os.Stdout)
 	// repl.FromFile("test.gwine")
 }
+
+// runLint implements `gwine lint [-enable a,b] [-disable a,b] [-list] file...`
+// and returns the process exit code.
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	enable := fs.String("enable", "", "comma separated checks to run, all others are disabled")
+	disable := fs.String("disable", "", "comma separated checks to skip")
+	list := fs.Bool("list", false, "list the available checks")
+	fs.Parse(args)
+
+	l := lint.New()
+	if *list {
+		for _, c := range l.Checks() {
+			fmt.Printf("%-12s %s\n", c.Name, c.Doc)
+		}
+		return 0
+	}
+	if *enable != "" {
+		for _, c := range l.Checks() {
+			l.Disable(c.Name)
+		}
+		for _, name := range strings.Split(*enable, ",") {
+			if err := l.Enable(name); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+	}
+	if *disable != "" {
+		for _, name := range strings.Split(*disable, ",") {
+			if err := l.Disable(name); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+	}
+
+	code := 0
+	for _, file := range fs.Args() {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 2
+			continue
+		}
+		diags, err := l.Lint(string(src))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			code = 2
+			continue
+		}
+		for _, d := range diags {
+			fmt.Printf("%s:%s\n", file, d)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+// loadProgram reads a gwine source file, or with fromJSON an AST encoded by
+// `gwine parse -json`.
+func loadProgram(file string, fromJSON bool) (*ast.Program, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if fromJSON {
+		program := &ast.Program{}
+		if err := program.UnmarshalJSON(src); err != nil {
+			return nil, err
+		}
+		return program, nil
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse error: %s", strings.Join(p.Errors(), "; "))
+	}
+	return program, nil
+}
+
+// runParse implements `gwine parse [-json] file`.
+func runParse(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the AST as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gwine parse [-json] file")
+		return 2
+	}
+
+	program, err := loadProgram(fs.Arg(0), false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !*asJSON {
+		fmt.Println(program.String())
+		return 0
+	}
+	data, err := ast.Marshal(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+// runFile implements `gwine run [-json] [-eval] [-path dirs] file`. The
+// module search path defaults to $GWINE_PATH.
+func runFile(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fromJSON := fs.Bool("json", false, "the file holds a JSON encoded AST")
+	eval := fs.Bool("eval", false, "use the tree-walking evaluator instead of the vm")
+	path := fs.String("path", os.Getenv("GWINE_PATH"), "list of directories searched for imported modules")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gwine run [-json] [-eval] [-path dirs] file")
+		return 2
+	}
+
+	program, err := loadProgram(fs.Arg(0), *fromJSON)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	run := repl.RunProgram
+	if *eval {
+		run = repl.EvalProgram
+	}
+	var searchPath []string
+	if *path != "" {
+		searchPath = filepath.SplitList(*path)
+	}
+	if err := run(program, fs.Arg(0), repl.NewLoader(searchPath...), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+// Package module finds, reads and parses the files named by import
+// statements. It is shared by the compiler and the evaluator: each of them
+// keeps the modules it has already run, the Loader only resolves paths and
+// reports import cycles.
+package module
+
+import (
+	"fmt"
+	"gwine/ast"
+	"gwine/lexer"
+	"gwine/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Loader resolves import paths against the importing file and a search
+// path, and tracks the modules being loaded to detect cycles.
+type Loader struct {
+	// SearchPath lists the directories tried, in order, when a path is not
+	// found next to the importing file.
+	SearchPath []string
+	// Transform, if not nil, is applied to every parsed module, e.g. to
+	// expand macros.
+	Transform func(*ast.Program) (*ast.Program, error)
+
+	loading []string
+}
+
+// NewLoader returns a Loader that searches the given directories.
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{SearchPath: searchPath}
+}
+
+// Resolve returns the absolute path of the file that path refers to when it
+// is imported from the file from. from may be empty for code that does not
+// come from a file, in which case the working directory is tried first.
+func (l *Loader) Resolve(path, from string) (string, error) {
+	if filepath.IsAbs(path) {
+		if isFile(path) {
+			return filepath.Clean(path), nil
+		}
+		return "", fmt.Errorf("cannot find module %q", path)
+	}
+
+	dirs := []string{"."}
+	if from != "" {
+		dirs[0] = filepath.Dir(from)
+	}
+	dirs = append(dirs, l.SearchPath...)
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, path)
+		if isFile(candidate) {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("cannot find module %q (looked in %s)", path, strings.Join(dirs, ", "))
+}
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// Begin marks path, as returned by Resolve, as being loaded. It fails if
+// path is already being loaded, that is if it imports itself through the
+// modules it imports. Every successful Begin must be paired with an End.
+func (l *Loader) Begin(path string) error {
+	for i, p := range l.loading {
+		if p == path {
+			cycle := []string{}
+			for _, q := range append(l.loading[i:], path) {
+				cycle = append(cycle, display(q))
+			}
+			return fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	l.loading = append(l.loading, path)
+	return nil
+}
+
+// End marks path as loaded.
+func (l *Loader) End(path string) {
+	for i := len(l.loading) - 1; i >= 0; i-- {
+		if l.loading[i] == path {
+			l.loading = append(l.loading[:i], l.loading[i+1:]...)
+			return
+		}
+	}
+}
+
+// display shortens path relative to the working directory when possible.
+func display(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// Load reads and parses the module at path and applies Transform to it.
+func (l *Loader) Load(path string) (*ast.Program, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: parse error: %s", display(path), strings.Join(p.Errors(), "; "))
+	}
+	if l.Transform != nil {
+		return l.Transform(program)
+	}
+	return program, nil
+}
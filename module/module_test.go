@@ -0,0 +1,78 @@
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, src string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	lib := t.TempDir()
+	main := writeFile(t, dir, "main.gw", "")
+	local := writeFile(t, dir, "sub/local.gw", "")
+	shared := writeFile(t, lib, "shared.gw", "")
+	writeFile(t, lib, "sub/local.gw", "")
+
+	l := NewLoader(lib)
+	tests := []struct {
+		path, want string
+	}{
+		{"sub/local.gw", local}, // next to the importer wins over the search path
+		{"shared.gw", shared},
+		{shared, shared},
+	}
+	for _, tt := range tests {
+		got, err := l.Resolve(tt.path, main)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if _, err := l.Resolve("missing.gw", main); err == nil || !strings.Contains(err.Error(), "cannot find module") {
+		t.Errorf("Resolve(missing.gw) error = %v", err)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	l := NewLoader()
+	for _, p := range []string{"/a.gw", "/b.gw"} {
+		if err := l.Begin(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := l.Begin("/a.gw")
+	if err == nil || !strings.HasSuffix(err.Error(), "a.gw -> /b.gw -> /a.gw") {
+		t.Fatalf("Begin(/a.gw) error = %v, want a cycle", err)
+	}
+	l.End("/b.gw")
+	l.End("/a.gw")
+	if err := l.Begin("/a.gw"); err != nil {
+		t.Fatalf("Begin after End: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeFile(t, dir, "bad.gw", "let = 1;")
+	if _, err := NewLoader().Load(bad); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("Load(bad.gw) error = %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+// Integers are INTEGER while they fit in an int64 and BIGINT beyond: + - *
+// ** and negation promote a result that overflows, and every result that
+// fits is an INTEGER again, so the two types never hold the same number.
+
+// maxBits bounds the size of computed integers, so that 2 ** 1000000000 is
+// an error rather than an attempt to allocate the result.
+const maxBits = 1 << 20
+
+// IsInteger reports whether obj is an INTEGER or a BIGINT.
+func IsInteger(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt:
+		return true
+	}
+	return false
+}
+
+// IntegerInfix applies the infix operator to two integers, INTEGER or
+// BIGINT. The vm and the evaluator both use it, so that they agree on every
+// operator and edge case.
+func IntegerInfix(operator string, left, right Object) Object {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if lok && rok {
+		if result, ok := smallInfix(operator, l.Value, r.Value); ok {
+			return result
+		}
+	}
+	return bigInfix(operator, toBig(left), toBig(right))
+}
+
+// IntegerPrefix applies - or ~ to an INTEGER or a BIGINT.
+func IntegerPrefix(operator string, operand Object) Object {
+	if i, ok := operand.(*Integer); ok && i.Value != math.MinInt64 {
+		if operator == "-" {
+			return &Integer{Value: -i.Value}
+		}
+		return &Integer{Value: ^i.Value}
+	}
+	if operator == "-" {
+		return normalize(new(big.Int).Neg(toBig(operand)))
+	}
+	return normalize(new(big.Int).Not(toBig(operand)))
+}
+
+// smallInfix computes with int64s; it reports false when the result does
+// not fit.
+func smallInfix(operator string, left, right int64) (Object, bool) {
+	switch operator {
+	case "+":
+		sum := left + right
+		if left > 0 && right > 0 && sum < 0 || left < 0 && right < 0 && sum >= 0 {
+			return nil, false
+		}
+		return &Integer{Value: sum}, true
+	case "-":
+		diff := left - right
+		if left >= 0 && right < 0 && diff < 0 || left < 0 && right > 0 && diff >= 0 {
+			return nil, false
+		}
+		return &Integer{Value: diff}, true
+	case "*":
+		product, ok := multiply(left, right)
+		if !ok {
+			return nil, false
+		}
+		return &Integer{Value: product}, true
+	case "/", "%":
+		if right == 0 {
+			return divisionByZero(operator), true
+		}
+		if left == math.MinInt64 && right == -1 {
+			return nil, false
+		}
+		if operator == "/" {
+			return &Integer{Value: left / right}, true
+		}
+		return &Integer{Value: left % right}, true
+	case "**":
+		if right < 0 {
+			return newError("negative exponent %d", right), true
+		}
+		result := int64(1)
+		for base, exp := left, right; exp > 0; exp >>= 1 {
+			var ok bool
+			if exp&1 == 1 {
+				if result, ok = multiply(result, base); !ok {
+					return nil, false
+				}
+			}
+			if exp > 1 {
+				if base, ok = multiply(base, base); !ok {
+					return nil, false
+				}
+			}
+		}
+		return &Integer{Value: result}, true
+	case "&":
+		return &Integer{Value: left & right}, true
+	case "|":
+		return &Integer{Value: left | right}, true
+	case "^":
+		return &Integer{Value: left ^ right}, true
+	case "<<", ">>":
+		if right < 0 {
+			return newError("negative shift count %d", right), true
+		}
+		if operator == "<<" {
+			shifted := left << uint64(right)
+			if shifted>>uint64(right) != left {
+				return nil, false
+			}
+			return &Integer{Value: shifted}, true
+		}
+		return &Integer{Value: left >> uint64(right)}, true
+	case "<":
+		return nativeBool(left < right), true
+	case ">":
+		return nativeBool(left > right), true
+	case "<=":
+		return nativeBool(left <= right), true
+	case ">=":
+		return nativeBool(left >= right), true
+	case "==":
+		return nativeBool(left == right), true
+	case "!=":
+		return nativeBool(left != right), true
+	}
+	return newError("unknown operator: %s %s %s", INTEGER_OBJ, operator, INTEGER_OBJ), true
+}
+
+// multiply returns left*right and whether it fits in an int64.
+func multiply(left, right int64) (int64, bool) {
+	if left == 0 || right == 0 {
+		return 0, true
+	}
+	product := left * right
+	if product/right != left || left == -1 && right == math.MinInt64 || right == -1 && left == math.MinInt64 {
+		return 0, false
+	}
+	return product, true
+}
+
+// bigInfix computes with big.Ints; shifts by a BIGINT and results of more
+// than maxBits are errors.
+func bigInfix(operator string, left, right *big.Int) Object {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(left, right)
+	case "-":
+		result.Sub(left, right)
+	case "*":
+		if left.BitLen()+right.BitLen() > maxBits {
+			return tooLarge()
+		}
+		result.Mul(left, right)
+	case "/", "%":
+		if right.Sign() == 0 {
+			return divisionByZero(operator)
+		}
+		// Quo and Rem truncate like Go's / and %, which INTEGERs use
+		if operator == "/" {
+			result.Quo(left, right)
+		} else {
+			result.Rem(left, right)
+		}
+	case "**":
+		if right.Sign() < 0 {
+			return newError("negative exponent %s", right)
+		}
+		if left.CmpAbs(big.NewInt(1)) > 0 && (!right.IsInt64() || right.Int64() > maxBits ||
+			int64(left.BitLen()-1)*right.Int64() > maxBits) {
+			return tooLarge()
+		}
+		result.Exp(left, right, nil)
+	case "&":
+		result.And(left, right)
+	case "|":
+		result.Or(left, right)
+	case "^":
+		result.Xor(left, right)
+	case "<<", ">>":
+		if right.Sign() < 0 {
+			return newError("negative shift count %s", right)
+		}
+		if operator == ">>" {
+			if !right.IsInt64() || right.Int64() > int64(left.BitLen()) {
+				// everything is shifted out
+				return &Integer{Value: int64(left.Sign() >> 1)}
+			}
+			result.Rsh(left, uint(right.Int64()))
+			break
+		}
+		if !right.IsInt64() || right.Int64() > maxBits-int64(left.BitLen()) {
+			return tooLarge()
+		}
+		result.Lsh(left, uint(right.Int64()))
+	case "<":
+		return nativeBool(left.Cmp(right) < 0)
+	case ">":
+		return nativeBool(left.Cmp(right) > 0)
+	case "<=":
+		return nativeBool(left.Cmp(right) <= 0)
+	case ">=":
+		return nativeBool(left.Cmp(right) >= 0)
+	case "==":
+		return nativeBool(left.Cmp(right) == 0)
+	case "!=":
+		return nativeBool(left.Cmp(right) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", INTEGER_OBJ, operator, INTEGER_OBJ)
+	}
+	return normalize(result)
+}
+
+func toBig(obj Object) *big.Int {
+	if i, ok := obj.(*Integer); ok {
+		return big.NewInt(i.Value)
+	}
+	return obj.(*BigInt).Value
+}
+
+// normalize returns i as an INTEGER if it fits, and as a BIGINT otherwise.
+func normalize(i *big.Int) Object {
+	if i.IsInt64() {
+		return &Integer{Value: i.Int64()}
+	}
+	return &BigInt{Value: i}
+}
+
+func divisionByZero(operator string) *Error {
+	if operator == "%" {
+		return newError("modulo by zero")
+	}
+	return newError("division by zero")
+}
+func tooLarge() *Error {
+	return newError("integer too large, more than %d bits", maxBits)
+}
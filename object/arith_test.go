@@ -0,0 +1,67 @@
+package object
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntegerInfix(t *testing.T) {
+	i := func(v int64) Object { return &Integer{Value: v} }
+	big := func(op string, l, r Object) Object { return IntegerInfix(op, l, r) }
+	two64 := big("**", i(2), i(64))
+
+	tests := []struct {
+		operator    string
+		left, right Object
+		expected    string
+		typ         ObjectType
+	}{
+		{"+", i(math.MaxInt64), i(1), "9223372036854775808", BIGINT_OBJ},
+		{"-", i(math.MinInt64), i(1), "-9223372036854775809", BIGINT_OBJ},
+		{"*", i(math.MinInt64), i(-1), "9223372036854775808", BIGINT_OBJ},
+		{"/", i(math.MinInt64), i(-1), "9223372036854775808", BIGINT_OBJ},
+		{"%", i(math.MinInt64), i(-1), "0", INTEGER_OBJ},
+		{"**", i(3), i(40), "12157665459056928801", BIGINT_OBJ},
+		{"**", i(-2), i(63), "-9223372036854775808", INTEGER_OBJ},
+		{"-", two64, two64, "0", INTEGER_OBJ},
+		{"/", two64, i(4), "4611686018427387904", INTEGER_OBJ},
+		{"%", i(-7), i(3), "-1", INTEGER_OBJ},
+		{"%", big("-", i(0), two64), i(3), "-1", INTEGER_OBJ},
+		{"<", i(1), two64, "true", BOOLEAN_OBJ},
+		{"==", two64, big("*", i(1<<32), i(1<<32)), "true", BOOLEAN_OBJ},
+		{"<<", i(1), i(64), "18446744073709551616", BIGINT_OBJ},
+		{"<<", i(1), i(63), "9223372036854775808", BIGINT_OBJ},
+		{"<<", i(-1), i(63), "-9223372036854775808", INTEGER_OBJ},
+		{"<<", i(3), i(1 << 21), "integer too large, more than 1048576 bits", ERROR_OBJ},
+		{"<<", i(48), i(math.MaxInt64), "integer too large, more than 1048576 bits", ERROR_OBJ},
+		{"<<", i(0), i(1000), "0", INTEGER_OBJ},
+		{">>", two64, i(1000), "0", INTEGER_OBJ},
+		{">>", big("-", i(0), two64), i(1000), "-1", INTEGER_OBJ},
+		{"/", i(1), i(0), "division by zero", ERROR_OBJ},
+		{"%", two64, i(0), "modulo by zero", ERROR_OBJ},
+		{"**", i(2), i(-1), "negative exponent -1", ERROR_OBJ},
+		{"<<", i(1), i(-1), "negative shift count -1", ERROR_OBJ},
+		{"**", i(10), i(1 << 30), "integer too large, more than 1048576 bits", ERROR_OBJ},
+		{"**", i(1), two64, "1", INTEGER_OBJ},
+	}
+	for n, tt := range tests {
+		got := IntegerInfix(tt.operator, tt.left, tt.right)
+		value := got.Inspect()
+		if e, ok := got.(*Error); ok {
+			value = e.Message
+		}
+		if value != tt.expected || got.Type() != tt.typ {
+			t.Errorf("test %v :expected %s %s,got %s %s", n, tt.typ, tt.expected, got.Type(), value)
+		}
+	}
+
+	if got := IntegerPrefix("-", i(math.MinInt64)); got.Inspect() != "9223372036854775808" {
+		t.Errorf("-MinInt64 = %s", got.Inspect())
+	}
+	if got := IntegerPrefix("-", big("+", i(math.MaxInt64), i(1))); got.Type() != INTEGER_OBJ {
+		t.Errorf("-(MaxInt64 + 1) should be an INTEGER, got %s", got.Type())
+	}
+	if two64.(Hashable).HashKey() != big("*", i(1<<32), i(1<<32)).(Hashable).HashKey() {
+		t.Errorf("equal BIGINTs should hash alike")
+	}
+}
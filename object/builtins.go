@@ -1,125 +1,639 @@
 package object
 
-var Builtins = []struct {
-	Name    string
-	Builtin *Builtin
-}{
-	{
-		Name: "len",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				if len(args) != 1 {
-					return newError("wrong number of arguments for len function")
-				}
-				switch arg := args[0].(type) {
-				case *String:
-					return &Integer{Value: int64(len(arg.Value))}
-				case *Array:
-					return &Integer{Value: int64(len(arg.Elements))}
-				default:
-					return newError("argument type %s for len not supported", arg.Type())
-				}
-			},
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Builtins is the registry of builtin functions shared by the vm and the
+// evaluator. The compiler refers to a builtin by its index here, so new
+// builtins are appended.
+var Builtins = []*Builtin{
+	{
+		Name:   "len",
+		Params: []Param{{"value", []ObjectType{STRING_OBJ, ARRAY_OBJ, HASH_OBJ}}},
+		Doc:    "returns the number of characters in a string, of elements in an array or of pairs in a hash",
+		Fn: func(args ...Object) Object {
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *Hash:
+				return &Integer{Value: int64(len(arg.Order))}
+			default:
+				return &Integer{Value: int64(len(arg.(*Array).Elements))}
+			}
 		},
 	},
 	{
-		Name: "first",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				if len(args) != 1{
-					return newError("wrong number of arguments for first function")
-				}
-				if args[0].Type() != ARRAY_OBJ{
-					return newError("first need argument array")
+		Name:   "first",
+		Params: []Param{{"array", []ObjectType{ARRAY_OBJ, GENERATOR_OBJ}}},
+		Doc:    "returns the first element of array, or null if it is empty; of a generator, its next value",
+		Fn: func(args ...Object) Object {
+			if g, ok := args[0].(*Generator); ok {
+				value, ok := g.Next()
+				if !ok && value == nil {
+					return NullObj
 				}
-				array := args[0].(*Array)
-				if len(array.Elements) > 0{
-					return array.Elements[0]
-				}
-				return NullObj
-			},
+				return value
+			}
+			array := args[0].(*Array)
+			if len(array.Elements) > 0 {
+				return array.Elements[0]
+			}
+			return NullObj
 		},
 	},
 	{
-		Name: "last",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				if len(args) != 1{
-					return newError("wrong number of arguments for last function")
-				}
-				if args[0].Type() != ARRAY_OBJ{
-					return newError("first need argument array")
-				}
-				array := args[0].(*Array)
-				if len(array.Elements) > 0{
-					return array.Elements[len(array.Elements)-1]
-				}
-				return NullObj
-			},
+		Name:   "last",
+		Params: []Param{{"array", []ObjectType{ARRAY_OBJ}}},
+		Doc:    "returns the last element of array, or null if it is empty",
+		Fn: func(args ...Object) Object {
+			array := args[0].(*Array)
+			if len(array.Elements) > 0 {
+				return array.Elements[len(array.Elements)-1]
+			}
+			return NullObj
 		},
 	},
 	{
-		Name: "head",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				if len(args) != 1{
-					return newError("wrong number of arguments for tail function")
-				}
-				if args[0].Type() != ARRAY_OBJ{
-					return newError("first need argument array")
-				}
-				array := args[0].(*Array)
-				length := len(array.Elements)
-				if len(array.Elements) > 0{
-					newElements := make([]Object,length-1,length-1)
-					copy(newElements,array.Elements[:length-1])
-					return &Array{Elements: newElements}
-				}
-				return NullObj
-			},
+		Name:   "head",
+		Params: []Param{{"array", []ObjectType{ARRAY_OBJ}}},
+		Doc:    "returns a new array without the last element of array, or null if it is empty",
+		Fn: func(args ...Object) Object {
+			array := args[0].(*Array)
+			length := len(array.Elements)
+			if length > 0 {
+				newElements := make([]Object, length-1)
+				copy(newElements, array.Elements[:length-1])
+				return &Array{Elements: newElements}
+			}
+			return NullObj
 		},
 	},
 	{
-		Name: "push",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				if len(args) != 2{
-					return newError("wrong number of arguments for push function")
-				}
-				if args[0].Type() != ARRAY_OBJ{
-					return newError("first need argument array")
-				}
-				array := args[0].(*Array)
-				length := len(array.Elements)
-				if len(array.Elements) > 0{
-					newElements := make([]Object,length+1,length+1)
-					copy(newElements,array.Elements)
-					newElements[length] = args[1]
-					return &Array{Elements: newElements}
-				}
-				return NullObj
-			},
+		Name:   "push",
+		Params: []Param{{"array", []ObjectType{ARRAY_OBJ}}, {"value", nil}},
+		Doc:    "returns a new array with value appended to array",
+		Fn: func(args ...Object) Object {
+			array := args[0].(*Array)
+			length := len(array.Elements)
+			newElements := make([]Object, length+1)
+			copy(newElements, array.Elements)
+			newElements[length] = args[1]
+			return &Array{Elements: newElements}
 		},
 	},
 	{
-		Name: "tail",
-		Builtin: &Builtin{
-			Fn: func(args ...Object) Object {
-				if len(args) != 1{
-					return newError("wrong number of arguments for tail function")
-				}
-				if args[0].Type() != ARRAY_OBJ{
-					return newError("first need argument array")
+		Name:   "tail",
+		Params: []Param{{"array", []ObjectType{ARRAY_OBJ}}},
+		Doc:    "returns a new array without the first element of array, or null if it is empty",
+		Fn: func(args ...Object) Object {
+			array := args[0].(*Array)
+			length := len(array.Elements)
+			if length > 0 {
+				newElements := make([]Object, length-1)
+				copy(newElements, array.Elements[1:length])
+				return &Array{Elements: newElements}
+			}
+			return NullObj
+		},
+	},
+	{
+		Name:   "help",
+		Params: []Param{{"builtin", []ObjectType{BUILTIN_OBJ}}},
+		Doc:    "returns the signature and documentation of a builtin function",
+		Fn: func(args ...Object) Object {
+			b := args[0].(*Builtin)
+			return &String{Value: b.Signature() + "\n    " + b.Doc}
+		},
+	},
+	{
+		Name:     "puts",
+		Params:   []Param{{"values", nil}},
+		Variadic: true,
+		Doc:      "writes values separated by spaces and followed by a newline",
+		IO: func(s *Streams, args ...Object) Object {
+			fmt.Fprintln(s.Out, joinInspect(args))
+			return NullObj
+		},
+	},
+	{
+		Name:     "print",
+		Params:   []Param{{"values", nil}},
+		Variadic: true,
+		Doc:      "writes values separated by spaces",
+		IO: func(s *Streams, args ...Object) Object {
+			fmt.Fprint(s.Out, joinInspect(args))
+			return NullObj
+		},
+	},
+	{
+		Name:     "printf",
+		Params:   []Param{{"format", []ObjectType{STRING_OBJ}}, {"values", nil}},
+		Variadic: true,
+		Doc:      "writes values formatted by format, see sprintf",
+		IO: func(s *Streams, args ...Object) Object {
+			out, err := Sprintf(args[0].(*String).Value, args[1:])
+			if err != nil {
+				return newError("printf: %s", err)
+			}
+			fmt.Fprint(s.Out, out)
+			return NullObj
+		},
+	},
+	{
+		Name:     "sprintf",
+		Params:   []Param{{"format", []ObjectType{STRING_OBJ}}, {"values", nil}},
+		Variadic: true,
+		Doc:      "returns values formatted by format: %d %x %o %b %c take integers, %t booleans, %q strings, %s and %v anything",
+		Fn: func(args ...Object) Object {
+			out, err := Sprintf(args[0].(*String).Value, args[1:])
+			if err != nil {
+				return newError("sprintf: %s", err)
+			}
+			return &String{Value: out}
+		},
+	},
+	{
+		Name:   "input",
+		Params: []Param{{"prompt", []ObjectType{STRING_OBJ}}},
+		Doc:    "writes prompt and returns the next line of input, or null at its end",
+		IO: func(s *Streams, args ...Object) Object {
+			fmt.Fprint(s.Out, args[0].(*String).Value)
+			return s.readLine()
+		},
+	},
+	{
+		Name: "read_line",
+		Doc:  "returns the next line of input without its line ending, or null at its end",
+		IO: func(s *Streams, args ...Object) Object {
+			return s.readLine()
+		},
+	},
+	{
+		Name:   "keys",
+		Params: []Param{{"hash", []ObjectType{HASH_OBJ}}},
+		Doc:    "returns the keys of hash in insertion order",
+		Fn: func(args ...Object) Object {
+			keys := []Object{}
+			for _, pair := range args[0].(*Hash).Ordered() {
+				keys = append(keys, pair.Key)
+			}
+			return &Array{Elements: keys}
+		},
+	},
+	{
+		Name:   "values",
+		Params: []Param{{"hash", []ObjectType{HASH_OBJ}}},
+		Doc:    "returns the values of hash in insertion order",
+		Fn: func(args ...Object) Object {
+			values := []Object{}
+			for _, pair := range args[0].(*Hash).Ordered() {
+				values = append(values, pair.Value)
+			}
+			return &Array{Elements: values}
+		},
+	},
+	{
+		Name:   "items",
+		Params: []Param{{"hash", []ObjectType{HASH_OBJ}}},
+		Doc:    "returns the [key, value] pairs of hash in insertion order",
+		Fn: func(args ...Object) Object {
+			items := []Object{}
+			for _, pair := range args[0].(*Hash).Ordered() {
+				items = append(items, &Array{Elements: []Object{pair.Key, pair.Value}})
+			}
+			return &Array{Elements: items}
+		},
+	},
+	{
+		Name:   "has",
+		Params: []Param{{"hash", []ObjectType{HASH_OBJ}}, {"key", hashKeyTypes}},
+		Doc:    "reports whether hash has key",
+		Fn: func(args ...Object) Object {
+			_, ok := args[0].(*Hash).Get(args[1].(Hashable))
+			if ok {
+				return True
+			}
+			return False
+		},
+	},
+	{
+		Name:   "delete",
+		Params: []Param{{"hash", []ObjectType{HASH_OBJ}}, {"key", hashKeyTypes}},
+		Doc:    "returns a new hash without key",
+		Fn: func(args ...Object) Object {
+			hash := args[0].(*Hash).Copy()
+			hash.Delete(args[1].(Hashable))
+			return hash
+		},
+	},
+	{
+		Name:     "merge",
+		Params:   []Param{{"hashes", []ObjectType{HASH_OBJ}}},
+		Variadic: true,
+		Doc:      "returns a new hash with the pairs of all hashes, later ones winning",
+		Fn: func(args ...Object) Object {
+			merged := NewHash()
+			for _, arg := range args {
+				for _, pair := range arg.(*Hash).Ordered() {
+					merged.Set(pair.Key.(Hashable), pair.Value)
 				}
-				array := args[0].(*Array)
-				length := len(array.Elements)
-				if len(array.Elements) > 0{
-					newElements := make([]Object,length-1,length-1)
-					copy(newElements,array.Elements[1:length])
-					return &Array{Elements: newElements}
+			}
+			return merged
+		},
+	},
+	{
+		Name:   "get",
+		Params: []Param{{"hash", []ObjectType{HASH_OBJ}}, {"key", hashKeyTypes}, {"default", nil}},
+		Doc:    "returns the value of key in hash, or default if it has none",
+		Fn: func(args ...Object) Object {
+			if value, ok := args[0].(*Hash).Get(args[1].(Hashable)); ok {
+				return value
+			}
+			return args[2]
+		},
+	},
+	{
+		Name:   "split",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"sep", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns the parts of s between the occurrences of sep; an empty sep splits s into characters",
+		Fn: func(args ...Object) Object {
+			return stringArray(strings.Split(args[0].(*String).Value, args[1].(*String).Value))
+		},
+	},
+	{
+		Name:   "join",
+		Params: []Param{{"strings", []ObjectType{ARRAY_OBJ, GENERATOR_OBJ}}, {"sep", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns the strings of an array or a generator joined by sep",
+		Fn: func(args ...Object) Object {
+			all := collect(Iterate(args[0]).(*Iterator), -1)
+			if err, ok := all.(*Error); ok {
+				return err
+			}
+			elements := all.(*Array).Elements
+			parts := make([]string, len(elements))
+			for i, el := range elements {
+				s, ok := el.(*String)
+				if !ok {
+					return newError("join: element %d must be STRING, got %s", i, el.Type())
 				}
-				return NullObj
-			},
+				parts[i] = s.Value
+			}
+			return &String{Value: strings.Join(parts, args[1].(*String).Value)}
+		},
+	},
+	{
+		Name:   "trim",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns s without leading and trailing white space",
+		Fn: func(args ...Object) Object {
+			return &String{Value: strings.TrimSpace(args[0].(*String).Value)}
+		},
+	},
+	{
+		Name:   "trim_prefix",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"prefix", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns s without prefix, or s if it does not start with prefix",
+		Fn: func(args ...Object) Object {
+			return &String{Value: strings.TrimPrefix(args[0].(*String).Value, args[1].(*String).Value)}
+		},
+	},
+	{
+		Name:   "trim_suffix",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"suffix", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns s without suffix, or s if it does not end with suffix",
+		Fn: func(args ...Object) Object {
+			return &String{Value: strings.TrimSuffix(args[0].(*String).Value, args[1].(*String).Value)}
 		},
 	},
+	{
+		Name:   "replace",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"old", []ObjectType{STRING_OBJ}}, {"new", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns s with every occurrence of old replaced by new",
+		Fn: func(args ...Object) Object {
+			return &String{Value: strings.ReplaceAll(args[0].(*String).Value, args[1].(*String).Value, args[2].(*String).Value)}
+		},
+	},
+	{
+		Name:   "upper",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns s in upper case",
+		Fn: func(args ...Object) Object {
+			return &String{Value: strings.ToUpper(args[0].(*String).Value)}
+		},
+	},
+	{
+		Name:   "lower",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns s in lower case",
+		Fn: func(args ...Object) Object {
+			return &String{Value: strings.ToLower(args[0].(*String).Value)}
+		},
+	},
+	{
+		Name:   "contains",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"sub", []ObjectType{STRING_OBJ}}},
+		Doc:    "reports whether sub occurs in s",
+		Fn: func(args ...Object) Object {
+			return nativeBool(strings.Contains(args[0].(*String).Value, args[1].(*String).Value))
+		},
+	},
+	{
+		Name:   "starts_with",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"prefix", []ObjectType{STRING_OBJ}}},
+		Doc:    "reports whether s starts with prefix",
+		Fn: func(args ...Object) Object {
+			return nativeBool(strings.HasPrefix(args[0].(*String).Value, args[1].(*String).Value))
+		},
+	},
+	{
+		Name:   "ends_with",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"suffix", []ObjectType{STRING_OBJ}}},
+		Doc:    "reports whether s ends with suffix",
+		Fn: func(args ...Object) Object {
+			return nativeBool(strings.HasSuffix(args[0].(*String).Value, args[1].(*String).Value))
+		},
+	},
+	{
+		Name:   "index_of",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"sub", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns the character index of the first occurrence of sub in s, or -1",
+		Fn: func(args ...Object) Object {
+			s := args[0].(*String).Value
+			return &Integer{Value: int64(runeIndex(s, strings.Index(s, args[1].(*String).Value)))}
+		},
+	},
+	{
+		Name:   "repeat",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}, {"count", []ObjectType{INTEGER_OBJ}}},
+		Doc:    "returns count copies of s",
+		Fn: func(args ...Object) Object {
+			count := args[1].(*Integer).Value
+			if count < 0 {
+				return newError("repeat: negative count %d", count)
+			}
+			s := args[0].(*String).Value
+			if len(s) > 0 && count > maxStringBytes/int64(len(s)) {
+				return newError("repeat: result too large, more than %d bytes", maxStringBytes)
+			}
+			return &String{Value: strings.Repeat(s, int(count))}
+		},
+	},
+	{
+		Name:   "chars",
+		Params: []Param{{"s", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns the characters of s as an array of strings",
+		Fn: func(args ...Object) Object {
+			return stringArray(strings.Split(args[0].(*String).Value, ""))
+		},
+	},
+	{
+		Name:   "ord",
+		Params: []Param{{"c", []ObjectType{STRING_OBJ}}},
+		Doc:    "returns the code point of the single character c",
+		Fn: func(args ...Object) Object {
+			runes := []rune(args[0].(*String).Value)
+			if len(runes) != 1 {
+				return newError("ord: want a single character, got %d", len(runes))
+			}
+			return &Integer{Value: int64(runes[0])}
+		},
+	},
+	{
+		Name:   "chr",
+		Params: []Param{{"code", []ObjectType{INTEGER_OBJ}}},
+		Doc:    "returns the character with code point code",
+		Fn: func(args ...Object) Object {
+			code := args[0].(*Integer).Value
+			if code < 0 || code > unicode.MaxRune || !utf8.ValidRune(rune(code)) {
+				return newError("chr: invalid code point %d", code)
+			}
+			return &String{Value: string(rune(code))}
+		},
+	},
+	{
+		Name:     "next",
+		Params:   []Param{{"generator", []ObjectType{GENERATOR_OBJ}}, {"default", nil}},
+		Variadic: true,
+		Doc:      "returns the next value of generator; once it is done, default if given and an error otherwise",
+		Fn: func(args ...Object) Object {
+			if len(args) > 2 {
+				return newError("wrong number of arguments for next: want at most 2, got %d", len(args))
+			}
+			value, ok := args[0].(*Generator).Next()
+			switch {
+			case ok, value != nil:
+				return value
+			case len(args) == 2:
+				return args[1]
+			}
+			return newError("next: generator is done")
+		},
+	},
+	{
+		Name:   "take",
+		Params: []Param{{"values", iterableTypes}, {"count", []ObjectType{INTEGER_OBJ}}},
+		Doc:    "returns an array of the first count values of an array, string, hash or generator",
+		Fn: func(args ...Object) Object {
+			count := args[1].(*Integer).Value
+			if count < 0 {
+				return newError("take: negative count %d", count)
+			}
+			return collect(Iterate(args[0]).(*Iterator), count)
+		},
+	},
+	{
+		Name:   "collect",
+		Params: []Param{{"values", iterableTypes}},
+		Doc:    "returns an array of the values of an array, string, hash or generator",
+		Fn: func(args ...Object) Object {
+			return collect(Iterate(args[0]).(*Iterator), -1)
+		},
+	},
+	{
+		Name:     "channel",
+		Params:   []Param{{"capacity", []ObjectType{INTEGER_OBJ}}},
+		Variadic: true,
+		Doc:      "returns a channel holding up to capacity values not received yet, none if capacity is not given",
+		Tasks: func(t *Tasks, args ...Object) Object {
+			if len(args) > 1 {
+				return newError("wrong number of arguments for channel: want at most 1, got %d", len(args))
+			}
+			capacity := int64(0)
+			if len(args) == 1 {
+				capacity = args[0].(*Integer).Value
+			}
+			if capacity < 0 || capacity > maxCapacity {
+				return newError("channel: invalid capacity %d", capacity)
+			}
+			return NewChannel(t, int(capacity))
+		},
+	},
+	{
+		Name:   "send",
+		Params: []Param{{"channel", []ObjectType{CHANNEL_OBJ}}, {"value", nil}},
+		Doc:    "sends value on channel, waiting while it is full",
+		Fn: func(args ...Object) Object {
+			if err := args[0].(*Channel).Send(args[1]); err != nil {
+				return err
+			}
+			return NullObj
+		},
+	},
+	{
+		Name:   "recv",
+		Params: []Param{{"channel", []ObjectType{CHANNEL_OBJ}}},
+		Doc:    "returns the next value sent on channel, waiting for one; null once it is closed and empty",
+		Fn: func(args ...Object) Object {
+			return args[0].(*Channel).Recv()
+		},
+	},
+	{
+		Name:   "close",
+		Params: []Param{{"channel", []ObjectType{CHANNEL_OBJ, GENERATOR_OBJ}}},
+		Doc:    "closes channel, after which sending on it is an error; closes a generator, which then yields nothing more",
+		Fn: func(args ...Object) Object {
+			var err *Error
+			switch arg := args[0].(type) {
+			case *Generator:
+				err = arg.Close()
+			default:
+				err = arg.(*Channel).Close()
+			}
+			if err != nil {
+				return err
+			}
+			return NullObj
+		},
+	},
+	{
+		Name:     "wait_all",
+		Params:   []Param{{"tasks", []ObjectType{TASK_OBJ, ARRAY_OBJ}}},
+		Variadic: true,
+		Doc:      "waits for tasks, given as tasks or arrays of them, and returns an array of their results",
+		Fn: func(args ...Object) Object {
+			return waitAll(args)
+		},
+	},
+}
+
+// maxCapacity bounds the capacity of channels, which is allocated at once.
+const maxCapacity = 1 << 20
+
+func nativeBool(b bool) *Boolean {
+	if b {
+		return True
+	}
+	return False
+}
+
+// hashKeyTypes are the types usable as hash keys.
+var hashKeyTypes = []ObjectType{STRING_OBJ, INTEGER_OBJ, BIGINT_OBJ, BOOLEAN_OBJ}
+
+// GetBuiltinByName returns the builtin called name, or nil.
+func GetBuiltinByName(name string) *Builtin {
+	for _, b := range Builtins {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Call checks args against the declared signature and calls the builtin;
+// s are the streams of the running program and t the state of its tasks.
+func (b *Builtin) Call(s *Streams, t *Tasks, args ...Object) Object {
+	args, err := Positional(b.Name, args)
+	if err != nil {
+		return err
+	}
+	if err := b.checkArgs(args); err != nil {
+		return err
+	}
+	if b.IO != nil {
+		if s != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+		}
+		return b.IO(s, args...)
+	}
+	if b.Tasks != nil {
+		return b.Tasks(t, args...)
+	}
+	return b.Fn(args...)
+}
+func (b *Builtin) checkArgs(args []Object) *Error {
+	min := len(b.Params)
+	if b.Variadic {
+		min--
+		if len(args) < min {
+			return newError("wrong number of arguments for %s: want at least %d, got %d", b.Name, min, len(args))
+		}
+	} else if len(args) != min {
+		return newError("wrong number of arguments for %s: want %d, got %d", b.Name, min, len(args))
+	}
+
+	for i, arg := range args {
+		param := b.Params[len(b.Params)-1]
+		if i < len(b.Params) {
+			param = b.Params[i]
+		}
+		if !param.accepts(arg) {
+			return newError("argument %d (%s) of %s must be %s, got %s",
+				i+1, param.Name, b.Name, param.typeList(), arg.Type())
+		}
+	}
+	return nil
+}
+func (p Param) accepts(arg Object) bool {
+	if p.Types == nil {
+		return true
+	}
+	for _, t := range p.Types {
+		if arg.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+// typeList returns the accepted types as "A, B or C".
+func (p Param) typeList() string {
+	names := strings.Split(p.typeNames(", "), ", ")
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+func (p Param) typeNames(sep string) string {
+	names := []string{}
+	for _, t := range p.Types {
+		names = append(names, string(t))
+	}
+	return strings.Join(names, sep)
+}
+
+// Signature returns the declaration of b, e.g. "push(array ARRAY, value)".
+func (b *Builtin) Signature() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for i, p := range b.Params {
+		param := p.Name
+		if b.Variadic && i == len(b.Params)-1 {
+			param += "..."
+		}
+		if p.Types != nil {
+			param += " " + p.typeNames("|")
+		}
+		params = append(params, param)
+	}
+	out.WriteString(b.Name)
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	return out.String()
 }
@@ -0,0 +1,206 @@
+package object
+
+import "testing"
+
+func TestBuiltinCall(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}}}
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string
+	}{
+		{"len", []Object{&String{Value: "four"}}, "4"},
+		{"len", []Object{}, "ERROR: wrong number of arguments for len: want 1, got 0"},
+		{"len", []Object{&Integer{Value: 1}}, "ERROR: argument 1 (value) of len must be STRING, ARRAY or HASH, got INTEGER"},
+		{"push", []Object{&Array{}, &Integer{Value: 2}}, "[2]"},
+		{"push", []Object{array, &Integer{Value: 2}}, "[1,2]"},
+		{"first", []Object{&Array{}}, "null"},
+		{"help", []Object{GetBuiltinByName("push")}, "push(array ARRAY, value)\n    returns a new array with value appended to array"},
+		{"help", []Object{NullObj}, "ERROR: argument 1 (builtin) of help must be BUILTIN, got NULL"},
+	}
+	for i, tt := range tests {
+		got := GetBuiltinByName(tt.name).Call(nil, nil, tt.args...).Inspect()
+		if got != tt.expected {
+			t.Errorf("test %v :expected %q,got %q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestVariadicBuiltin(t *testing.T) {
+	b := &Builtin{
+		Name:     "sum",
+		Params:   []Param{{"first", []ObjectType{INTEGER_OBJ}}, {"rest", []ObjectType{INTEGER_OBJ}}},
+		Variadic: true,
+		Fn:       func(args ...Object) Object { return &Integer{Value: int64(len(args))} },
+	}
+	if got := b.Signature(); got != "sum(first INTEGER, rest... INTEGER)" {
+		t.Errorf("Signature() = %q", got)
+	}
+	if got := b.Call(nil, nil, &Integer{Value: 1}).Inspect(); got != "1" {
+		t.Errorf("sum(1) = %q", got)
+	}
+	if got := b.Call(nil, nil).Inspect(); got != "ERROR: wrong number of arguments for sum: want at least 1, got 0" {
+		t.Errorf("sum() = %q", got)
+	}
+	if got := b.Call(nil, nil, &Integer{Value: 1}, &Integer{Value: 2}, True).Inspect(); got != "ERROR: argument 3 (rest) of sum must be INTEGER, got BOOLEAN" {
+		t.Errorf("sum(1, 2, true) = %q", got)
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	tests := []struct {
+		format   string
+		args     []Object
+		expected string
+	}{
+		{"%d%%", []Object{&Integer{Value: 42}}, "42%"},
+		{"%05d|%-4s|%x", []Object{&Integer{Value: 7}, &String{Value: "ab"}, &Integer{Value: 255}}, "00007|ab  |ff"},
+		{"%t %q %v", []Object{True, &String{Value: "hi"}, &Array{Elements: []Object{&Integer{Value: 1}}}}, `true "hi" [1]`},
+		{"%d", []Object{&String{Value: "x"}}, "error: %d expects INTEGER, got STRING"},
+		{"%d %d", []Object{&Integer{Value: 1}}, "error: missing argument for %d"},
+		{"%d", []Object{&Integer{Value: 1}, &Integer{Value: 2}}, "error: 1 arguments are not used by the format"},
+		{"%y", []Object{&Integer{Value: 1}}, "error: unknown verb %y"},
+	}
+	for i, tt := range tests {
+		got, err := Sprintf(tt.format, tt.args)
+		if err != nil {
+			got = "error: " + err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("test %v :expected %q,got %q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	a, b, c := &String{Value: "a"}, &String{Value: "b"}, &Integer{Value: 3}
+	h := NewHash()
+	h.Set(b, &Integer{Value: 1})
+	h.Set(a, &Integer{Value: 2})
+	h.Set(c, True)
+	other := NewHash()
+	other.Set(a, &Integer{Value: 20})
+	other.Set(&String{Value: "z"}, NullObj)
+
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string
+	}{
+		{"len", []Object{h}, "3"},
+		{"keys", []Object{h}, "[b,a,3]"},
+		{"values", []Object{h}, "[1,2,true]"},
+		{"items", []Object{h}, "[[b,1],[a,2],[3,true]]"},
+		{"has", []Object{h, c}, "true"},
+		{"has", []Object{h, &String{Value: "c"}}, "false"},
+		{"has", []Object{h, &Array{}}, "ERROR: argument 2 (key) of has must be STRING, INTEGER, BIGINT or BOOLEAN, got ARRAY"},
+		{"delete", []Object{h, a}, "{b: 1, 3: true}"},
+		{"merge", []Object{h, other}, "{b: 1, a: 20, 3: true, z: null}"},
+		{"merge", []Object{}, "{}"},
+		{"get", []Object{h, a, NullObj}, "2"},
+		{"get", []Object{h, &String{Value: "c"}, &Integer{Value: 0}}, "0"},
+	}
+	for i, tt := range tests {
+		got := GetBuiltinByName(tt.name).Call(nil, nil, tt.args...).Inspect()
+		if got != tt.expected {
+			t.Errorf("test %v %s :expected %q,got %q", i, tt.name, tt.expected, got)
+		}
+	}
+	if h.Inspect() != "{b: 1, a: 2, 3: true}" {
+		t.Errorf("delete or merge changed their argument: %s", h.Inspect())
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	str := func(s string) Object { return &String{Value: s} }
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string
+	}{
+		{"len", []Object{str("héllo")}, "5"},
+		{"split", []Object{str("a,b,,c"), str(",")}, "[a,b,,c]"},
+		{"split", []Object{str("hé"), str("")}, "[h,é]"},
+		{"join", []Object{&Array{Elements: []Object{str("a"), str("b")}}, str("-")}, "a-b"},
+		{"join", []Object{&Array{Elements: []Object{str("a"), &Integer{Value: 1}}}, str("-")}, "ERROR: join: element 1 must be STRING, got INTEGER"},
+		{"trim", []Object{str(" \tgw\n")}, "gw"},
+		{"trim_prefix", []Object{str("gwine"), str("gw")}, "ine"},
+		{"trim_suffix", []Object{str("gwine"), str("x")}, "gwine"},
+		{"replace", []Object{str("a.b.c"), str("."), str("/")}, "a/b/c"},
+		{"upper", []Object{str("héllo")}, "HÉLLO"},
+		{"lower", []Object{str("GW")}, "gw"},
+		{"contains", []Object{str("gwine"), str("win")}, "true"},
+		{"starts_with", []Object{str("gwine"), str("wi")}, "false"},
+		{"ends_with", []Object{str("gwine"), str("ne")}, "true"},
+		{"index_of", []Object{str("héllo"), str("l")}, "2"},
+		{"index_of", []Object{str("héllo"), str("z")}, "-1"},
+		{"repeat", []Object{str("ab"), &Integer{Value: 3}}, "ababab"},
+		{"repeat", []Object{str("ab"), &Integer{Value: -1}}, "ERROR: repeat: negative count -1"},
+		{"repeat", []Object{str("ab"), &Integer{Value: 9223372036854775807}}, "ERROR: repeat: result too large, more than 268435456 bytes"},
+		{"repeat", []Object{str(""), &Integer{Value: 9223372036854775807}}, ""},
+		{"chars", []Object{str("日本")}, "[日,本]"},
+		{"ord", []Object{str("é")}, "233"},
+		{"ord", []Object{str("ab")}, "ERROR: ord: want a single character, got 2"},
+		{"chr", []Object{&Integer{Value: 26085}}, "日"},
+		{"chr", []Object{&Integer{Value: -1}}, "ERROR: chr: invalid code point -1"},
+	}
+	for i, tt := range tests {
+		got := GetBuiltinByName(tt.name).Call(nil, nil, tt.args...).Inspect()
+		if got != tt.expected {
+			t.Errorf("test %v :expected %q,got %q", i, tt.expected, got)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	s := &String{Value: "héllo"}
+	array := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}, &Integer{Value: 3}}}
+	i := func(v int64) Object { return &Integer{Value: v} }
+	tests := []struct {
+		left             Object
+		start, end, step Object
+		expected         string
+	}{
+		{s, i(1), i(3), NullObj, "él"},
+		{s, NullObj, i(2), NullObj, "hé"},
+		{s, i(3), NullObj, NullObj, "lo"},
+		{s, i(4), i(2), NullObj, ""},
+		{s, i(-3), i(99), NullObj, "llo"},
+		{s, i(-99), i(-1), NullObj, "héll"},
+		{s, NullObj, NullObj, i(2), "hlo"},
+		{s, NullObj, NullObj, i(-1), "olléh"},
+		{s, i(3), i(0), i(-1), "llé"},
+		{s, i(99), i(-99), i(-2), "olh"},
+		{s, NullObj, NullObj, i(1 << 62), "h"},
+		{s, NullObj, NullObj, i(-1 << 62), "o"},
+		{array, i(1), NullObj, NullObj, "[2,3]"},
+		{array, NullObj, NullObj, NullObj, "[1,2,3]"},
+		{array, NullObj, i(-1), NullObj, "[1,2]"},
+		{array, NullObj, NullObj, i(-2), "[3,1]"},
+		{&Array{}, NullObj, NullObj, i(-1), "[]"},
+		{array, NullObj, NullObj, i(0), "ERROR: slice step cannot be zero"},
+		{array, True, NullObj, NullObj, "ERROR: slice index must be INTEGER, got BOOLEAN"},
+		{array, NullObj, NullObj, s, "ERROR: slice step must be INTEGER, got STRING"},
+		{&Integer{Value: 1}, NullObj, NullObj, NullObj, "ERROR: slice operator not supported INTEGER"},
+	}
+	for n, tt := range tests {
+		if got := Slice(tt.left, tt.start, tt.end, tt.step).Inspect(); got != tt.expected {
+			t.Errorf("test %v :expected %q,got %q", n, tt.expected, got)
+		}
+	}
+	if got := IndexString(s, 1).Inspect(); got != "é" {
+		t.Errorf("IndexString(s, 1) = %q", got)
+	}
+	if got := IndexString(s, 5); got != NullObj {
+		t.Errorf("IndexString(s, 5) = %v", got.Inspect())
+	}
+	if got := IndexString(s, -1).Inspect(); got != "o" {
+		t.Errorf("IndexString(s, -1) = %q", got)
+	}
+	if got := IndexArray(array, -3).Inspect(); got != "1" {
+		t.Errorf("IndexArray(array, -3) = %q", got)
+	}
+	if got := IndexArray(array, -4); got != NullObj {
+		t.Errorf("IndexArray(array, -4) = %v", got.Inspect())
+	}
+}
@@ -0,0 +1,174 @@
+package object
+
+import (
+	"gwine/ast"
+	"strings"
+)
+
+// The vm and the evaluator both match the arguments of calls to parameters
+// with these, so that they agree on what a call passes and on its errors.
+
+// Spread and Keyword are the arguments ...Value and Name: Value of a call.
+// They only live from the evaluation of the arguments to the call, which
+// takes them apart.
+type Spread struct {
+	Value Object
+}
+
+func (s *Spread) Type() ObjectType { return SPREAD_OBJ }
+func (s *Spread) Inspect() string  { return "..." + s.Value.Inspect() }
+
+type Keyword struct {
+	Name  string
+	Value Object
+}
+
+func (k *Keyword) Type() ObjectType { return KEYWORD_OBJ }
+func (k *Keyword) Inspect() string  { return k.Name + ": " + k.Value.Inspect() }
+
+// Signature describes the parameters of a function: their Names, the
+// first Required of which have no default, and with Rest set, the last one
+// gets an array of the positional arguments beyond the others.
+type Signature struct {
+	Names    []string
+	Required int
+	Rest     bool
+}
+
+// NewSignature returns the signature of the parameters of fn.
+func NewSignature(fn *ast.FunctionLiteral) Signature {
+	s := Signature{Names: make([]string, len(fn.Parameters)), Rest: fn.Rest}
+	for i, p := range fn.Parameters {
+		s.Names[i] = p.Value
+		if fn.Default(i) == nil && !(fn.Rest && i == len(fn.Parameters)-1) {
+			s.Required++
+		}
+	}
+	return s
+}
+
+// Bind matches args, the arguments of a call of the function called name,
+// to the parameters of s. It returns the value of each parameter, nil for
+// one left to its default, or an *Error if they do not fit.
+func (s *Signature) Bind(name string, args []Object) ([]Object, *Error) {
+	if !s.Rest && len(args) == len(s.Names) && !special(args) {
+		return args, nil
+	}
+	if name == "" {
+		name = "fn"
+	}
+	positional, keywords, err := splitArguments(args)
+	if err != nil {
+		return nil, err
+	}
+
+	named := len(s.Names)
+	if s.Rest {
+		named--
+	}
+	if len(positional) > named && !s.Rest || len(positional) < s.Required && len(keywords) == 0 {
+		return nil, s.arityError(name, len(positional))
+	}
+	values := make([]Object, len(s.Names))
+	copy(values[:named], positional)
+	if s.Rest {
+		rest := []Object{}
+		if len(positional) > named {
+			rest = append(rest, positional[named:]...)
+		}
+		values[named] = &Array{Elements: rest}
+	}
+	for _, k := range keywords {
+		i := indexOf(s.Names[:named], k.Name)
+		if i < 0 {
+			return nil, newError("%s has no parameter %s", name, k.Name)
+		}
+		if values[i] != nil {
+			return nil, newError("%s got two values for parameter %s", name, k.Name)
+		}
+		values[i] = k.Value
+	}
+	for i := 0; i < s.Required; i++ {
+		if values[i] == nil {
+			return nil, newError("%s is missing an argument for parameter %s", name, s.Names[i])
+		}
+	}
+	return values, nil
+}
+
+func (s *Signature) arityError(name string, got int) *Error {
+	named := len(s.Names)
+	if s.Rest {
+		named--
+	}
+	switch {
+	case s.Rest:
+		return newError("wrong number of arguments for %s: want at least %d, got %d", name, s.Required, got)
+	case s.Required == named:
+		return newError("wrong number of arguments for %s: want %d, got %d", name, named, got)
+	}
+	return newError("wrong number of arguments for %s: want %d to %d, got %d", name, s.Required, named, got)
+}
+
+// Positional returns args with their spreads expanded, or an *Error if
+// there is a keyword among them; the function called name takes
+// positional arguments only.
+func Positional(name string, args []Object) ([]Object, *Error) {
+	if !special(args) {
+		return args, nil
+	}
+	positional, keywords, err := splitArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(keywords) > 0 {
+		names := make([]string, len(keywords))
+		for i, k := range keywords {
+			names[i] = k.Name
+		}
+		return nil, newError("%s takes no keyword arguments, got %s", name, strings.Join(names, ", "))
+	}
+	return positional, nil
+}
+
+// special reports whether there is a *Spread or a *Keyword in args.
+func special(args []Object) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *Spread, *Keyword:
+			return true
+		}
+	}
+	return false
+}
+
+// splitArguments returns the positional arguments in args, with the
+// elements of spreads in their place, and the keyword ones.
+func splitArguments(args []Object) ([]Object, []*Keyword, *Error) {
+	var positional []Object
+	var keywords []*Keyword
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *Spread:
+			array, ok := arg.Value.(*Array)
+			if !ok {
+				return nil, nil, newError("cannot spread %s, want ARRAY", arg.Value.Type())
+			}
+			positional = append(positional, array.Elements...)
+		case *Keyword:
+			keywords = append(keywords, arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return positional, keywords, nil
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,362 @@
+package object
+
+import "sync"
+
+// Tasks run concurrently, each made by spawn with a call to run. Values are
+// immutable once made, so tasks share them freely; what changes is
+// synchronized where it lives:
+//
+//   - a channel passes values between tasks; a send happens before the
+//     receive that gets its value completes, and a close before a receive
+//     that returns null because of it
+//   - a task's result is handed to wait_all, the call happening before
+//     wait_all returns
+//   - tasks share the globals with the code spawning them, seeing a global
+//     redefined after they were spawned as functions do; which value a task
+//     reads of one redefined meanwhile is up to timing unless a channel
+//     orders the two
+//   - the builtins writing and reading the program's streams run one at a
+//     time, and a generator runs for one task at a time
+//
+// When every task of a program, the top level included, waits on a channel
+// or a task with none left running to end the wait, the program is
+// deadlocked: each of the waits raises an error instead.
+
+// Tasks is the state of the tasks of a program, kept by its channels and
+// tasks: it counts the tasks running, as opposed to waiting on channels and
+// other tasks, to tell when they are deadlocked. Its lock guards the
+// channels, tasks and waiters of the program, which are so few that waits
+// on any of them take turns.
+type Tasks struct {
+	sync.Mutex
+	running int // the top level and the tasks not done, less those waiting
+	waiting map[*waiter]bool
+}
+
+// NewTasks returns the state of the tasks of a program about to run, its
+// top level the one task running.
+func NewTasks() *Tasks {
+	return &Tasks{running: 1, waiting: make(map[*waiter]bool)}
+}
+
+// waiter is a task waiting for one of a few channel operations, or for
+// tasks to finish; whichever ends the wait sets what it found.
+type waiter struct {
+	woken chan struct{}
+	done  bool
+
+	index int    // of the operation that went on, -1 for a deadlock
+	value Object // received, or the *Error ending the wait
+	open  bool   // the value was sent, not that of a closed channel
+}
+
+func newWaiter() *waiter {
+	return &waiter{woken: make(chan struct{}, 1)}
+}
+
+// wait waits for w to be woken, with t locked before and after.
+func (t *Tasks) wait(w *waiter) {
+	t.waiting[w] = true
+	t.running--
+	t.deadlock()
+	t.Unlock()
+	<-w.woken
+	t.Lock()
+}
+
+// wake ends the wait of w, with t locked.
+func (t *Tasks) wake(w *waiter, index int, value Object, open bool) {
+	w.done = true
+	w.index, w.value, w.open = index, value, open
+	delete(t.waiting, w)
+	t.running++
+	w.woken <- struct{}{}
+}
+
+// deadlock ends every wait with an error if no task is left running to end
+// them, with t locked.
+func (t *Tasks) deadlock() {
+	if t.running > 0 {
+		return
+	}
+	for w := range t.waiting {
+		t.wake(w, -1, newError("deadlock: all tasks are waiting"), false)
+	}
+}
+
+// Channel passes values between tasks, in the order they were sent.
+type Channel struct {
+	tasks    *Tasks
+	capacity int
+	buffer   []Object // sent and not received yet
+	closed   bool
+
+	// the waiters receiving and sending in the order they came, those done
+	// since by another operation left to skip
+	recvs, sends []pending
+}
+
+// pending is the operation a waiter waits on a channel for.
+type pending struct {
+	w     *waiter
+	index int
+	value Object // to send
+}
+
+// NewChannel returns a channel of the program of t, holding up to capacity
+// values that were sent and not received yet.
+func NewChannel(t *Tasks, capacity int) *Channel {
+	return &Channel{tasks: t, capacity: capacity}
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string  { return "channel" }
+
+// Send sends value, waiting while the channel is full.
+func (c *Channel) Send(value Object) *Error {
+	c.tasks.Lock()
+	defer c.tasks.Unlock()
+	if ok, err := c.send(value); ok || err != nil {
+		return err
+	}
+	w := newWaiter()
+	c.sends = append(c.sends, pending{w: w, value: value})
+	c.tasks.wait(w)
+	c.sends = without(c.sends, w)
+	if err, ok := w.value.(*Error); ok {
+		return err
+	}
+	return nil
+}
+
+// Recv returns the next value sent, waiting for one; a closed channel
+// returns null once its values were received.
+func (c *Channel) Recv() Object {
+	value, _ := c.receive()
+	return value
+}
+
+// receive is Recv, also reporting whether the value was sent rather than
+// that of a closed channel; a wait raising an error returns it and false.
+func (c *Channel) receive() (Object, bool) {
+	c.tasks.Lock()
+	defer c.tasks.Unlock()
+	if value, open, ok := c.recv(); ok {
+		return value, open
+	}
+	w := newWaiter()
+	c.recvs = append(c.recvs, pending{w: w})
+	c.tasks.wait(w)
+	c.recvs = without(c.recvs, w)
+	return w.value, w.open
+}
+
+// Close closes the channel: sending is an error from then on.
+func (c *Channel) Close() *Error {
+	c.tasks.Lock()
+	defer c.tasks.Unlock()
+	if c.closed {
+		return newError("close of closed channel")
+	}
+	c.closed = true
+	for p, ok := next(&c.recvs); ok; p, ok = next(&c.recvs) {
+		c.tasks.wake(p.w, p.index, NullObj, false)
+	}
+	for p, ok := next(&c.sends); ok; p, ok = next(&c.sends) {
+		c.tasks.wake(p.w, -1, newError("send on closed channel"), false)
+	}
+	return nil
+}
+
+// send sends value if it can without waiting, with c.tasks locked.
+func (c *Channel) send(value Object) (bool, *Error) {
+	if c.closed {
+		return false, newError("send on closed channel")
+	}
+	if p, ok := next(&c.recvs); ok {
+		c.tasks.wake(p.w, p.index, value, true)
+		return true, nil
+	}
+	if len(c.buffer) < c.capacity {
+		c.buffer = append(c.buffer, value)
+		return true, nil
+	}
+	return false, nil
+}
+
+// recv receives a value if it can without waiting, with c.tasks locked.
+func (c *Channel) recv() (value Object, open, ok bool) {
+	if len(c.buffer) > 0 {
+		value, c.buffer = c.buffer[0], c.buffer[1:]
+		if p, ok := next(&c.sends); ok {
+			c.buffer = append(c.buffer, p.value)
+			c.tasks.wake(p.w, p.index, NullObj, true)
+		}
+		return value, true, true
+	}
+	if p, ok := next(&c.sends); ok {
+		c.tasks.wake(p.w, p.index, NullObj, true)
+		return p.value, true, true
+	}
+	return NullObj, false, c.closed
+}
+
+// next removes the first operation of queue whose waiter is not done.
+func next(queue *[]pending) (pending, bool) {
+	for len(*queue) > 0 {
+		p := (*queue)[0]
+		*queue = (*queue)[1:]
+		if !p.w.done {
+			return p, true
+		}
+	}
+	return pending{}, false
+}
+
+// without returns queue without the operations of w.
+func without(queue []pending, w *waiter) []pending {
+	kept := queue[:0]
+	for _, p := range queue {
+		if p.w != w {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// Task is the value of a spawn, the call it runs.
+type Task struct {
+	tasks   *Tasks
+	done    bool
+	result  Object
+	waiters []*waiter
+}
+
+// NewTask returns the task of a call about to run in the program of t,
+// counted as running until it is finished.
+func NewTask(t *Tasks) *Task {
+	t.Lock()
+	defer t.Unlock()
+	t.running++
+	return &Task{tasks: t}
+}
+
+func (t *Task) Type() ObjectType { return TASK_OBJ }
+func (t *Task) Inspect() string  { return "task" }
+
+// Finish sets the value the call returned, or the *Error it raised.
+func (t *Task) Finish(result Object) {
+	t.tasks.Lock()
+	defer t.tasks.Unlock()
+	t.done, t.result = true, result
+	for _, w := range t.waiters {
+		if !w.done {
+			t.tasks.wake(w, 0, NullObj, true)
+		}
+	}
+	t.waiters = nil
+	t.tasks.running--
+	t.tasks.deadlock()
+}
+
+// Wait waits for the call to finish and returns its result.
+func (t *Task) Wait() Object {
+	t.tasks.Lock()
+	defer t.tasks.Unlock()
+	if !t.done {
+		w := newWaiter()
+		t.waiters = append(t.waiters, w)
+		t.tasks.wait(w)
+		if w.index < 0 {
+			return w.value
+		}
+	}
+	return t.result
+}
+
+// SelectCase is the channel operation of a case of a select: a send of
+// Value if Send is set, and a receive otherwise.
+type SelectCase struct {
+	Channel Object
+	Send    bool
+	Value   Object
+}
+
+// Select makes the operation of the first of cases, on channels of the
+// program of t, that can go on and returns its index with the value
+// received, null for a send. If none can, it returns len(cases) and null
+// when hasDefault is set, and otherwise waits for one to. A case on a
+// non-channel is an *Error returned with index -1, like a send on a closed
+// channel.
+func Select(t *Tasks, cases []SelectCase, hasDefault bool) (int, Object) {
+	channels := make([]*Channel, len(cases))
+	for i, c := range cases {
+		ch, ok := c.Channel.(*Channel)
+		if !ok {
+			return -1, newError("select case %d must be CHANNEL, got %s", i+1, c.Channel.Type())
+		}
+		channels[i] = ch
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	for i, c := range cases {
+		if c.Send {
+			if ok, err := channels[i].send(c.Value); err != nil {
+				return -1, err
+			} else if ok {
+				return i, NullObj
+			}
+		} else if value, _, ok := channels[i].recv(); ok {
+			return i, value
+		}
+	}
+	if hasDefault {
+		return len(cases), NullObj
+	}
+	w := newWaiter()
+	for i, c := range cases {
+		if c.Send {
+			channels[i].sends = append(channels[i].sends, pending{w: w, index: i, value: c.Value})
+		} else {
+			channels[i].recvs = append(channels[i].recvs, pending{w: w, index: i})
+		}
+	}
+	t.wait(w)
+	for _, ch := range channels {
+		ch.recvs = without(ch.recvs, w)
+		ch.sends = without(ch.sends, w)
+	}
+	return w.index, w.value
+}
+
+// waitAll waits for every task in args, or in the arrays in args, and
+// returns an array of their results, or the *Error of the first one that
+// raised one.
+func waitAll(args []Object) Object {
+	var tasks []Object
+	for _, arg := range args {
+		if array, ok := arg.(*Array); ok {
+			tasks = append(tasks, array.Elements...)
+		} else {
+			tasks = append(tasks, arg)
+		}
+	}
+	for i, t := range tasks {
+		if _, ok := t.(*Task); !ok {
+			return newError("wait_all: element %d must be TASK, got %s", i+1, t.Type())
+		}
+	}
+	results := make([]Object, len(tasks))
+	var first *Error
+	for i, t := range tasks {
+		results[i] = t.(*Task).Wait()
+		if err, ok := results[i].(*Error); ok && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return first
+	}
+	return &Array{Elements: results}
+}
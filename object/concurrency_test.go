@@ -0,0 +1,33 @@
+package object
+
+import (
+	"runtime"
+	"testing"
+)
+
+// TestDeadlockPerProgram deadlocks one program while the top level of
+// another waits on a task still running, which must go on waiting.
+func TestDeadlockPerProgram(t *testing.T) {
+	a, b := NewTasks(), NewTasks()
+	task := NewTask(a)
+	done := make(chan Object)
+	go func() { done <- task.Wait() }()
+	for waiting := 0; waiting == 0; runtime.Gosched() {
+		a.Lock()
+		waiting = len(a.waiting)
+		a.Unlock()
+	}
+
+	if got := NewChannel(b, 0).Recv(); got.Inspect() != "ERROR: deadlock: all tasks are waiting" {
+		t.Errorf("recv in b = %s, want the deadlock error", got.Inspect())
+	}
+	select {
+	case result := <-done:
+		t.Fatalf("wait in a ended with %s before the task finished", result.Inspect())
+	default:
+	}
+	task.Finish(&Integer{Value: 1})
+	if result := <-done; result.Inspect() != "1" {
+		t.Errorf("wait in a = %s, want 1", result.Inspect())
+	}
+}
@@ -1,5 +1,12 @@
 package object
 
+import (
+	"gwine/ast"
+	"gwine/module"
+	"io"
+	"sync"
+)
+
 func NewEnvironment() *Environment {
 	return &Environment{store: make(map[string]Object), outer: nil}
 }
@@ -9,13 +16,197 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewCallEnvironment returns the environment of a call of fn made from
+// caller, which Trace follows back.
+func NewCallEnvironment(fn *Function, caller *Environment) *Environment {
+	env := NewEnclosedEnvironment(fn.Env)
+	env.function = TraceName(fn.Name)
+	env.caller = caller
+	return env
+}
+
+// NewModuleEnvironment returns the top-level environment of the module at
+// file, sharing the module state of importer.
+func NewModuleEnvironment(importer *Environment, file string) *Environment {
+	env := NewEnvironment()
+	env.modules = importer.Modules()
+	env.streams = importer.Streams()
+	env.tasks = importer.Tasks()
+	env.file = file
+	return env
+}
+
 type Environment struct {
+	mu    sync.RWMutex // guards store, which the tasks spawned from it share
 	store map[string]Object
 	outer *Environment
+
+	// consts maps the constants of store to the const statements that
+	// declared them
+	consts map[string]ast.Node
+
+	// set on top-level environments only
+	modules *Modules
+	file    string
+	exports map[string]bool
+	streams *Streams
+	tasks   *Tasks
+
+	// set on the environments of function calls only
+	function string
+	caller   *Environment
+	defers   []Deferred
+	yield    func(Object) // set on the calls of generators
+}
+
+// Deferred is a call deferred until the function making it returns.
+type Deferred struct {
+	Fn   Object
+	Args []Object
+}
+
+// Modules is the module state shared by a program and every module it
+// imports: the loader, and the modules evaluated so far by path.
+type Modules struct {
+	Loader *module.Loader
+	loaded map[string]*Module
+}
+
+func NewModules(loader *module.Loader) *Modules {
+	return &Modules{Loader: loader, loaded: make(map[string]*Module)}
+}
+func (m *Modules) Get(path string) (*Module, bool) {
+	mod, ok := m.loaded[path]
+	return mod, ok
+}
+func (m *Modules) Add(mod *Module) {
+	m.loaded[mod.Path] = mod
+}
+
+func (e *Environment) root() *Environment {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e
+}
+
+// TopLevel reports whether e is the environment of a program or module
+// rather than of a function call.
+func (e *Environment) TopLevel() bool {
+	return e.outer == nil
+}
+
+// SetModules sets the module state of the top-level environment and the
+// file its code comes from, against which imports are resolved.
+func (e *Environment) SetModules(m *Modules, file string) {
+	root := e.root()
+	root.modules = m
+	root.file = file
+}
+
+// Modules returns the module state, creating one with a default loader if
+// none was set.
+func (e *Environment) Modules() *Modules {
+	root := e.root()
+	if root.modules == nil {
+		root.modules = NewModules(module.NewLoader())
+	}
+	return root.modules
+}
+
+// SetStreams sets the input and output of the program.
+func (e *Environment) SetStreams(in io.Reader, out io.Writer) {
+	e.root().streams = NewStreams(in, out)
+}
+
+// Streams returns the input and output of the program, stdin and stdout if
+// none were set.
+func (e *Environment) Streams() *Streams {
+	root := e.root()
+	if root.streams == nil {
+		root.streams = DefaultStreams()
+	}
+	return root.streams
+}
+
+// Tasks returns the state of the tasks of the program.
+func (e *Environment) Tasks() *Tasks {
+	root := e.root()
+	if root.tasks == nil {
+		root.tasks = NewTasks()
+	}
+	return root.tasks
+}
+
+// File returns the file the code of this environment comes from, or "".
+func (e *Environment) File() string {
+	return e.root().file
+}
+
+// Trace returns the names of the functions being called when code runs in
+// e, innermost first, ending with TraceMain.
+func (e *Environment) Trace() []string {
+	trace := []string{}
+	for env := e; env != nil; {
+		if env.caller != nil {
+			trace = append(trace, env.function)
+			env = env.caller
+			continue
+		}
+		env = env.outer
+	}
+	return append(trace, TraceMain)
+}
+
+// Defer records a call to make when the call of e returns.
+func (e *Environment) Defer(d Deferred) {
+	e.defers = append(e.defers, d)
+}
+
+// PopDeferred removes and returns the call deferred last.
+func (e *Environment) PopDeferred() (Deferred, bool) {
+	if len(e.defers) == 0 {
+		return Deferred{}, false
+	}
+	d := e.defers[len(e.defers)-1]
+	e.defers = e.defers[:len(e.defers)-1]
+	return d, true
+}
+
+// SetYield makes yield in the call of a generator whose environment is e
+// hand its values to y.
+func (e *Environment) SetYield(y func(Object)) {
+	e.yield = y
+}
+
+// Yield hands value to the generator the code running in e belongs to.
+func (e *Environment) Yield(value Object) {
+	for env := e; env != nil; env = env.outer {
+		if env.yield != nil {
+			env.yield(value)
+			return
+		}
+	}
+}
+
+// Export marks name as visible to modules importing this one.
+func (e *Environment) Export(name string) {
+	root := e.root()
+	if root.exports == nil {
+		root.exports = make(map[string]bool)
+	}
+	root.exports[name] = true
+}
+
+// Exported reports whether name was exported.
+func (e *Environment) Exported(name string) bool {
+	return e.root().exports[name]
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
 	if !ok {
 		if e.outer != nil {
 			obj, ok = e.outer.Get(name)
@@ -23,7 +214,32 @@ func (e *Environment) Get(name string) (Object, bool) {
 	}
 	return obj, ok
 }
+
+// Set binds name to obj in e and returns obj, or leaves e alone and returns
+// an *Error if name is a constant of e.
 func (e *Environment) Set(name string, obj Object) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.consts[name]; ok {
+		return newError("cannot reassign constant %s", name)
+	}
+	e.store[name] = obj
+	return obj
+}
+
+// SetConst binds name to obj in e as a constant declared by decl, which is
+// the only statement that may bind it again, as it does when run again in a
+// loop. It returns obj, or an *Error like Set.
+func (e *Environment) SetConst(name string, obj Object, decl ast.Node) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if d, ok := e.consts[name]; ok && d != decl {
+		return newError("cannot reassign constant %s", name)
+	}
+	if e.consts == nil {
+		e.consts = make(map[string]ast.Node)
+	}
+	e.consts[name] = decl
 	e.store[name] = obj
 	return obj
 }
@@ -0,0 +1,71 @@
+package object
+
+// Exception is what a try catches: an error raised at run time, such as a
+// division by zero or a builtin rejecting its arguments, or a value thrown
+// by throw. Both engines raise and catch the same exceptions; the vm returns
+// an uncaught one from Run as an error.
+type Exception struct {
+	Message string
+	Value   Object   // the thrown value, nil for runtime errors
+	Trace   []string // the functions being called, innermost first
+}
+
+func (e *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (e *Exception) Inspect() string  { return "exception(" + e.Message + ")" }
+
+// Error returns the message reported when e is not caught.
+func (e *Exception) Error() string {
+	if e.Value == nil {
+		return e.Message
+	}
+	return "uncaught exception: " + e.Message
+}
+
+// TraceMain names the top level of a program in traces, and anonymous
+// functions are named by TraceName.
+const TraceMain = "<main>"
+
+// TraceName returns the name of a function in traces.
+func TraceName(name string) string {
+	if name == "" {
+		return "<anonymous>"
+	}
+	return name
+}
+
+// NewException returns the exception raised by throw value. Throwing an
+// exception again raises it unchanged, keeping its trace.
+func NewException(value Object, trace []string) *Exception {
+	if e, ok := value.(*Exception); ok {
+		return e
+	}
+	return &Exception{Message: value.Inspect(), Value: value, Trace: trace}
+}
+
+// IndexException returns e["message"], e["value"] or e["trace"]; the value
+// of a runtime error is null.
+func IndexException(e *Exception, key Object) Object {
+	field, ok := key.(*String)
+	if !ok {
+		return newError("exception field must be STRING, got %s", key.Type())
+	}
+	switch field.Value {
+	case "message":
+		return &String{Value: e.Message}
+	case "value":
+		if e.Value == nil {
+			return NullObj
+		}
+		return e.Value
+	case "trace":
+		return stringArray(e.Trace)
+	}
+	return newError("exception has no field %s", field.Value)
+}
+
+// Handler is an entry of the exception handler table of a function: the
+// positions of the catch and finally blocks of a try, -1 for a missing one.
+type Handler struct {
+	Catch   int
+	Finally int
+}
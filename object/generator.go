@@ -0,0 +1,139 @@
+package object
+
+import "sync"
+
+// Generator is the value of calling a function that yields. Each call of
+// Next runs the function on from where it stopped to its next yield; the
+// engine running the function provides Resume, and Stop if a generator
+// holds anything beyond itself until it is done.
+type Generator struct {
+	// Resume runs the function to its next yield and returns the value
+	// yielded and true, or false once the function returns; the value is
+	// then nil, or the *Error the function raised.
+	Resume func() (Object, bool)
+	// Stop, if set, abandons the function where it stopped; its pending
+	// defers do not run. Close calls it once, on a generator not done.
+	Stop func()
+
+	mu            sync.Mutex
+	running, done bool
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string  { return "generator" }
+
+// Next returns the next value of g like Resume does. A generator that is
+// done yields nothing more, and one running, resumed by itself or by
+// another task, cannot be resumed.
+func (g *Generator) Next() (Object, bool) {
+	g.mu.Lock()
+	if g.done {
+		g.mu.Unlock()
+		return nil, false
+	}
+	if g.running {
+		g.mu.Unlock()
+		return newError("generator is already running"), false
+	}
+	g.running = true
+	g.mu.Unlock()
+
+	value, ok := g.Resume()
+
+	g.mu.Lock()
+	g.running = false
+	g.done = !ok
+	g.mu.Unlock()
+	return value, ok
+}
+
+// Close makes g done, so that it yields nothing more. Closing a generator
+// that is done does nothing, and one running cannot be closed.
+func (g *Generator) Close() *Error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running {
+		return newError("generator is already running")
+	}
+	if !g.done && g.Stop != nil {
+		g.Stop()
+	}
+	g.done = true
+	return nil
+}
+
+// Iterator steps through the elements of an ARRAY, the characters of a
+// STRING, the keys of a HASH in order, the values of a GENERATOR or those
+// received from a CHANNEL until it is closed, for for ... in and the
+// builtins taking any of them.
+type Iterator struct {
+	next func() (Object, bool)
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "iterator" }
+
+// Next returns the next value and true, or false when there is none left;
+// the value is then nil, or the *Error a generator or the wait on a channel
+// raised.
+func (it *Iterator) Next() (Object, bool) {
+	return it.next()
+}
+
+// iterableTypes are the types Iterate accepts.
+var iterableTypes = []ObjectType{ARRAY_OBJ, STRING_OBJ, HASH_OBJ, GENERATOR_OBJ, CHANNEL_OBJ}
+
+// Iterate returns an *Iterator over obj, or an *Error if obj is not
+// iterable.
+func Iterate(obj Object) Object {
+	var values []Object
+	switch obj := obj.(type) {
+	case *Generator:
+		return &Iterator{next: obj.Next}
+	case *Channel:
+		return &Iterator{next: func() (Object, bool) {
+			value, open := obj.receive()
+			if !open && value == NullObj {
+				return nil, false
+			}
+			return value, open
+		}}
+	case *Array:
+		values = obj.Elements
+	case *String:
+		for _, r := range obj.Value {
+			values = append(values, &String{Value: string(r)})
+		}
+	case *Hash:
+		for _, pair := range obj.Ordered() {
+			values = append(values, pair.Key)
+		}
+	default:
+		return newError("%s is not iterable", obj.Type())
+	}
+	i := 0
+	return &Iterator{next: func() (Object, bool) {
+		if i == len(values) {
+			return nil, false
+		}
+		i++
+		return values[i-1], true
+	}}
+}
+
+// collect returns an array of the values of it, at most limit of them if
+// limit is not negative, or the *Error a generator raised.
+func collect(it *Iterator, limit int64) Object {
+	elements := []Object{}
+	for limit < 0 || int64(len(elements)) < limit {
+		value, ok := it.Next()
+		if !ok {
+			if value != nil {
+				return value
+			}
+			break
+		}
+		elements = append(elements, value)
+	}
+	return &Array{Elements: elements}
+}
@@ -0,0 +1,120 @@
+package object
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Streams are the standard input and output of a running program. The vm
+// and the evaluator hand theirs to every builtin they call.
+type Streams struct {
+	Out io.Writer
+	In  *bufio.Reader
+
+	mu sync.Mutex // held by the builtin using them, for tasks to take turns
+}
+
+func NewStreams(in io.Reader, out io.Writer) *Streams {
+	return &Streams{Out: out, In: bufio.NewReader(in)}
+}
+
+// DefaultStreams returns streams reading stdin and writing stdout.
+func DefaultStreams() *Streams {
+	return NewStreams(os.Stdin, os.Stdout)
+}
+
+// readLine reads a line without its line ending; at the end of the input it
+// returns null.
+func (s *Streams) readLine() Object {
+	line, err := s.In.ReadString('\n')
+	if err != nil && line == "" {
+		return NullObj
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &String{Value: line}
+}
+
+// Sprintf formats args like fmt.Sprintf. The verbs are checked against the
+// gwine types: %d, %x, %o, %b and %c take an INTEGER (all but %c also a
+// BIGINT), %t a BOOLEAN, %q a STRING, %s and %v any object; flags, width
+// and precision are passed on.
+func Sprintf(format string, args []Object) (string, error) {
+	var out strings.Builder
+
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return "", fmt.Errorf("format ends in an incomplete verb %q", format[start:])
+		}
+		verb := format[i]
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if next == len(args) {
+			return "", fmt.Errorf("missing argument for %%%c", verb)
+		}
+		value, err := formatValue(verb, args[next])
+		if err != nil {
+			return "", err
+		}
+		next++
+		fmt.Fprintf(&out, format[start:i+1], value)
+	}
+	if next < len(args) {
+		return "", fmt.Errorf("%d arguments are not used by the format", len(args)-next)
+	}
+	return out.String(), nil
+}
+
+// formatValue returns the Go value fmt should format arg as for verb.
+func formatValue(verb byte, arg Object) (interface{}, error) {
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b', 'c':
+		if i, ok := arg.(*Integer); ok {
+			return i.Value, nil
+		}
+		if i, ok := arg.(*BigInt); ok && verb != 'c' {
+			return i.Value, nil
+		}
+		if s, ok := arg.(*String); ok && (verb == 'x' || verb == 'X') {
+			return s.Value, nil
+		}
+		return nil, fmt.Errorf("%%%c expects INTEGER, got %s", verb, arg.Type())
+	case 't':
+		if b, ok := arg.(*Boolean); ok {
+			return b.Value, nil
+		}
+		return nil, fmt.Errorf("%%t expects BOOLEAN, got %s", arg.Type())
+	case 'q':
+		if s, ok := arg.(*String); ok {
+			return s.Value, nil
+		}
+		return nil, fmt.Errorf("%%q expects STRING, got %s", arg.Type())
+	case 's', 'v':
+		return arg.Inspect(), nil
+	}
+	return nil, fmt.Errorf("unknown verb %%%c", verb)
+}
+
+func joinInspect(args []Object) string {
+	values := make([]string, len(args))
+	for i, a := range args {
+		values[i] = a.Inspect()
+	}
+	return strings.Join(values, " ")
+}
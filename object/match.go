@@ -0,0 +1,42 @@
+package object
+
+// The vm and the evaluator both match patterns with these, so that they
+// agree on what matches.
+
+// MatchLiteral reports whether value equals literal, the value of a literal
+// pattern, and is of the same type.
+func MatchLiteral(literal, value Object) bool {
+	v, ok := value.(Hashable)
+	return ok && value.Type() == literal.Type() && v.HashKey() == literal.(Hashable).HashKey()
+}
+
+// MatchArray reports whether value is an array of min to n elements, or
+// of at least min if rest is set.
+func MatchArray(value Object, min, n int, rest bool) bool {
+	array, ok := value.(*Array)
+	return ok && len(array.Elements) >= min && (rest || len(array.Elements) <= n)
+}
+
+// MatchHash reports whether value is a hash having every one of keys.
+func MatchHash(value Object, keys []Object) bool {
+	hash, ok := value.(*Hash)
+	if !ok {
+		return false
+	}
+	for _, k := range keys {
+		if _, ok := hash.Get(k.(Hashable)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// NoMatch is the error of a match none of whose arms matches value.
+func NoMatch(value Object) *Error {
+	return newError("non-exhaustive match: no arm matches %s", value.Inspect())
+}
+
+// Mismatch is the error of a let whose pattern value does not match.
+func Mismatch(pattern string, value Object) *Error {
+	return newError("%s does not match the pattern %s", value.Inspect(), pattern)
+}
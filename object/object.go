@@ -6,6 +6,7 @@ import (
 	"gwine/ast"
 	"gwine/code"
 	"hash/fnv"
+	"math/big"
 	"strings"
 )
 
@@ -13,6 +14,7 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ = "INTEGER"
+	BIGINT_OBJ  = "BIGINT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	STRING_OBJ  = "STRING"
 	NULL_OBJ    = "NULL"
@@ -26,11 +28,25 @@ const (
 	BUILTIN_OBJ           = "BUILTIN"
 	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
 
+	QUOTE_OBJ = "QUOTE"
+	MACRO_OBJ = "MACRO"
+
+	MODULE_OBJ = "MODULE"
+
 	MEMBER_OBJ = "MEMBER"
 	STRUCT_OBJ = "STRUCT"
 	TYPE_OBJ   = "TYPE"
 
-	ERROR_OBJ = "ERROR"
+	ERROR_OBJ     = "ERROR"
+	EXCEPTION_OBJ = "EXCEPTION"
+
+	GENERATOR_OBJ = "GENERATOR"
+	ITERATOR_OBJ  = "ITERATOR"
+	CHANNEL_OBJ   = "CHANNEL"
+	TASK_OBJ      = "TASK"
+
+	SPREAD_OBJ  = "SPREAD"
+	KEYWORD_OBJ = "KEYWORD"
 )
 
 var True = &Boolean{Value: true}
@@ -49,6 +65,14 @@ type Integer struct {
 func (I *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// BigInt is an integer that does not fit in an int64, see IntegerInfix.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (b *BigInt) Inspect() string  { return b.Value.String() }
+
 type Boolean struct {
 	Value bool
 }
@@ -70,6 +94,10 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
 	Message string
+
+	// Exception is the exception the error raises in a try: set by throw,
+	// and on runtime errors by the evaluator once it knows their trace
+	Exception *Exception
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -77,8 +105,12 @@ func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // see ast.FunctionLiteral
+	Signature  Signature
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string
+	Generator  bool // calling it returns a generator, see ast.FunctionLiteral.IsGenerator
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -99,10 +131,55 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+// Module is an imported file evaluated by the evaluator; its exports are
+// the exported names of Env.
+type Module struct {
+	Path string
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module(" + m.Path + ")" }
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ","))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
 type CompiledFunction struct {
 	Instructions  code.Instructions
 	NumLocals     int
 	NumParameters int
+	Signature     Signature // the parameters, which are the first locals
+	Name          string
+	Handlers      []Handler // indexed by the operand of code.OpTry
+	Generator     bool      // calling it returns a generator
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
@@ -118,8 +195,32 @@ func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
 type BuiltinFunction func(args ...Object) Object
+
+// StreamFunction is a builtin that reads or writes the program's streams.
+type StreamFunction func(s *Streams, args ...Object) Object
+
+// TaskFunction is a builtin that makes channels for the program's tasks.
+type TaskFunction func(t *Tasks, args ...Object) Object
+
+// Builtin is a function implemented in Go. Its signature is declared so that
+// Call can check the arguments before Fn runs: Fn may assume it gets
+// len(Params) arguments, or at least len(Params)-1 when Variadic, each of an
+// accepted type. Builtins doing I/O set IO instead of Fn, and those making
+// channels set Tasks.
 type Builtin struct {
-	Fn BuiltinFunction
+	Name     string
+	Params   []Param
+	Variadic bool // the last parameter takes any number of arguments
+	Doc      string
+	Fn       BuiltinFunction
+	IO       StreamFunction
+	Tasks    TaskFunction
+}
+
+// Param is a builtin parameter. A nil Types accepts any object.
+type Param struct {
+	Name  string
+	Types []ObjectType
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -170,21 +271,82 @@ func (b *Boolean) HashKey() HashKey {
 func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value.Bytes())
+	return HashKey{Type: b.Type(), Value: h.Sum64() ^ uint64(b.Value.Sign())}
+}
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// Hash keeps its keys in insertion order in Order, so that iterating and
+// printing it is deterministic. Use Set and Delete to keep Pairs and Order
+// in step.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Order []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set binds key to value; a new key goes after all others.
+func (h *Hash) Set(key Hashable, value Object) {
+	hk := key.HashKey()
+	if _, ok := h.Pairs[hk]; !ok {
+		h.Order = append(h.Order, hk)
+	}
+	h.Pairs[hk] = HashPair{Key: key.(Object), Value: value}
+}
+
+// Get returns the value bound to key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Delete removes key.
+func (h *Hash) Delete(key Hashable) {
+	hk := key.HashKey()
+	if _, ok := h.Pairs[hk]; !ok {
+		return
+	}
+	delete(h.Pairs, hk)
+	for i, k := range h.Order {
+		if k == hk {
+			h.Order = append(h.Order[:i:i], h.Order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Ordered returns the pairs in insertion order.
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, len(h.Order))
+	for i, k := range h.Order {
+		pairs[i] = h.Pairs[k]
+	}
+	return pairs
+}
+
+// Copy returns a hash with the same pairs.
+func (h *Hash) Copy() *Hash {
+	cp := NewHash()
+	for _, pair := range h.Ordered() {
+		cp.Set(pair.Key.(Hashable), pair.Value)
+	}
+	return cp
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	out.WriteString("{")
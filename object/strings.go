@@ -0,0 +1,151 @@
+package object
+
+import "unicode/utf8"
+
+// Strings are sequences of runes: len, indexing, slicing and the string
+// builtins all count in runes, never in bytes, so that s[len(s) - 1] is the
+// last character of any UTF-8 string.
+
+// maxStringBytes bounds the size of strings built by repeat, so that
+// repeat("ab", 1 << 62) is an error rather than an attempt to allocate it.
+const maxStringBytes = 1 << 28
+
+// IndexString returns the character at index of s as a string, or null if
+// index is out of range. A negative index counts from the end.
+func IndexString(s *String, index int64) Object {
+	runes := []rune(s.Value)
+	i, ok := elementIndex(index, len(runes))
+	if !ok {
+		return NullObj
+	}
+	return &String{Value: string(runes[i])}
+}
+
+// IndexArray returns the element at index of a, or null if index is out of
+// range. A negative index counts from the end.
+func IndexArray(a *Array, index int64) Object {
+	i, ok := elementIndex(index, len(a.Elements))
+	if !ok {
+		return NullObj
+	}
+	return a.Elements[i]
+}
+func elementIndex(index int64, length int) (int, bool) {
+	if index < 0 {
+		index += int64(length)
+	}
+	if index < 0 || index >= int64(length) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+// Slice returns left[start:end:step] for an ARRAY or a STRING, the way
+// Python slices: start, end and step are INTEGER or NULL for the default,
+// negative bounds count from the end, out of range bounds are clamped and a
+// negative step walks backwards from the end.
+func Slice(left, start, end, step Object) Object {
+	var length int64
+	switch left := left.(type) {
+	case *Array:
+		length = int64(len(left.Elements))
+	case *String:
+		length = int64(utf8.RuneCountInString(left.Value))
+	default:
+		return newError("slice operator not supported %s", left.Type())
+	}
+
+	by := int64(1)
+	switch step := step.(type) {
+	case *Null:
+	case *Integer:
+		if step.Value == 0 {
+			return newError("slice step cannot be zero")
+		}
+		by = step.Value
+	default:
+		return newError("slice step must be INTEGER, got %s", step.Type())
+	}
+	from, to := int64(0), length
+	if by < 0 {
+		from, to = length-1, -1
+	}
+	from, err := sliceBound(start, from, length, by)
+	if err != nil {
+		return err
+	}
+	if to, err = sliceBound(end, to, length, by); err != nil {
+		return err
+	}
+
+	// a step longer than left takes one element at most; capping it keeps
+	// i += by from overflowing
+	if by > length {
+		by = length + 1
+	} else if by < -length {
+		by = -length - 1
+	}
+	var indices []int64
+	for i := from; by > 0 && i < to || by < 0 && i > to; i += by {
+		indices = append(indices, i)
+	}
+	if array, ok := left.(*Array); ok {
+		elements := make([]Object, len(indices))
+		for n, i := range indices {
+			elements[n] = array.Elements[i]
+		}
+		return &Array{Elements: elements}
+	}
+	runes := []rune(left.(*String).Value)
+	sliced := make([]rune, len(indices))
+	for n, i := range indices {
+		sliced[n] = runes[i]
+	}
+	return &String{Value: string(sliced)}
+}
+
+// sliceBound returns the value of a slice bound, missing if it is null. A
+// bound is resolved against length like Python does: negative ones count
+// from the end, and out of range ones stop at the first or last position
+// that step can reach.
+func sliceBound(bound Object, missing, length, step int64) (int64, *Error) {
+	switch bound := bound.(type) {
+	case *Null:
+		return missing, nil
+	case *Integer:
+		i := bound.Value
+		if i < 0 {
+			i += length
+		}
+		switch {
+		case i < 0 && step < 0:
+			return -1, nil
+		case i < 0:
+			return 0, nil
+		case i >= length && step < 0:
+			return length - 1, nil
+		case i >= length:
+			return length, nil
+		}
+		return i, nil
+	default:
+		return 0, newError("slice index must be INTEGER, got %s", bound.Type())
+	}
+}
+
+// stringArray returns an array of the strings in values.
+func stringArray(values []string) *Array {
+	elements := make([]Object, len(values))
+	for i, v := range values {
+		elements[i] = &String{Value: v}
+	}
+	return &Array{Elements: elements}
+}
+
+// runeIndex turns the byte offset i of s into a rune index; -1 stays -1.
+func runeIndex(s string, i int) int {
+	if i < 0 {
+		return i
+	}
+	return utf8.RuneCountInString(s[:i])
+}
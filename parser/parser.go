@@ -11,27 +11,50 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	TERNARY     // c ? a : b
+	NULLISH     // ??
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
 	LESSGREATER // < / >
-	SUM         // +
-	PRODUCT     // *
-	PREFIX      // -X / !X
+	PIPELINE    // |>
+	SUM         // + / | / ^
+	PRODUCT     // * / % / & / << / >>
+	PREFIX      // -X / !X / ~X
+	POWER       // **, binds tighter than a prefix on its left: -2 ** 2 is -4
 	CALL        // function(x)
 	INDEX
 	NAMEDECLARION
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NEQ:      EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.QUESTION:  TERNARY,
+	token.NULLISH:   NULLISH,
+	token.OR:        OR,
+	token.AND:       AND,
+	token.EQ:        EQUALS,
+	token.NEQ:       EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.LE:        LESSGREATER,
+	token.GE:        LESSGREATER,
+	token.PIPELINE:  PIPELINE,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.PIPE:      SUM,
+	token.CARET:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.AMPERSAND: PRODUCT,
+	token.SHL:       PRODUCT,
+	token.SHR:       PRODUCT,
+	token.POWER:     POWER,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
+	token.QDOT:      INDEX,
+	token.QLBRACKET: INDEX,
 }
 
 type Parser struct {
@@ -41,6 +64,10 @@ type Parser struct {
 
 	errors []string
 
+	// guard is set while parsing a match guard, whose end is marked by a
+	// =>, so that x if a > b => ... does not take b => ... for a function
+	guard bool
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -66,12 +93,21 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.SELECT, p.parseSelectExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseInterpolatedString)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
@@ -84,8 +120,17 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	for _, t := range []token.TokenType{token.LE, token.GE, token.PERCENT, token.POWER, token.AND, token.OR,
+		token.AMPERSAND, token.PIPE, token.CARET, token.SHL, token.SHR, token.NULLISH} {
+		p.registerInfix(t, p.parseInfixExpression)
+	}
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseSelectorExpression)
+	p.registerInfix(token.QLBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.QDOT, p.parseOptionalSelector)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.PIPELINE, p.parsePipeline)
 
 	p.nextToken()
 	p.nextToken()
@@ -136,12 +181,28 @@ func (p *Parser) ParseProgram() *ast.Program {
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
+		if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+			return p.parseLetPatternStatement()
+		}
+		return p.parseLetStatement()
+	case token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
 	case token.STRUCT:
 		return p.parseStructDeclarionStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	case token.FUNCTION:
+		if !p.peekTokenIs(token.IDENT) {
+			return p.parseExpressionStatement()
+		}
 		return p.parseFunctionDeclarionStatement()
 	default:
 		return p.parseExpressionStatement()
@@ -166,6 +227,12 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 func (p *Parser) parseIdentifier() ast.Expression {
+	if p.peekTokenIs(token.ARROW) && !p.guard {
+		fn := &ast.FunctionLiteral{Token: arrowToken(p.curToken)}
+		fn.Parameters = []*ast.Identifier{{Token: p.curToken, Value: p.curToken.Literal}}
+		p.nextToken()
+		return p.parseArrowBody(fn)
+	}
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 func (p *Parser) parseIntegerLiteral() ast.Expression {
@@ -183,10 +250,12 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
 
 	return array
 }
 func (p *Parser) parseHashLiteral() ast.Expression {
+	defer p.unguard()()
 	hash := &ast.HashLiteral{Token: p.curToken}
 	hash.Paris = make(map[ast.Expression]ast.Expression)
 
@@ -199,6 +268,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 		hash.Paris[key] = value
+		hash.Keys = append(hash.Keys, key)
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -206,12 +276,14 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Rbrace = p.curToken
 	return hash
 }
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	defer p.unguard()()
 	list := []ast.Expression{}
 
-	if p.peekTokenIs(token.RBRACKET) {
+	if p.peekTokenIs(end) {
 		p.nextToken()
 		return list
 	}
@@ -234,6 +306,69 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
+	if !p.expectPeek(token.LPAREN) || !p.parseParameters(lit) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	lit.Body = p.parseBlockStatement()
+	return lit
+
+}
+
+// parseParameters parses the parameters of fn, which may have defaults
+// and end with a ...rest parameter, up to the closing parenthesis.
+func (p *Parser) parseParameters(fn *ast.FunctionLiteral) bool {
+	fn.Parameters = []*ast.Identifier{}
+	for !p.peekTokenIs(token.RPAREN) {
+		if fn.Rest {
+			p.errors = append(p.errors, fmt.Sprintf("...%s must be the last parameter", fn.Parameters[len(fn.Parameters)-1].Value))
+			return false
+		}
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			fn.Rest = true
+		}
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		for _, other := range fn.Parameters {
+			if other.Value == param.Value {
+				p.errors = append(p.errors, fmt.Sprintf("duplicate parameter %s", param.Value))
+				return false
+			}
+		}
+		fn.Parameters = append(fn.Parameters, param)
+
+		if !fn.Rest && p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			dflt := p.parseExpression(LOWEST)
+			if dflt == nil {
+				return false
+			}
+			for len(fn.Defaults) < len(fn.Parameters)-1 {
+				fn.Defaults = append(fn.Defaults, nil)
+			}
+			fn.Defaults = append(fn.Defaults, dflt)
+		} else if !fn.Rest && fn.Defaults != nil {
+			p.errors = append(p.errors, fmt.Sprintf("parameter %s must have a default, following one that has", param.Value))
+			return false
+		}
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return false
+		}
+	}
+	p.nextToken()
+
+	return true
+}
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
@@ -244,7 +379,6 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 	lit.Body = p.parseBlockStatement()
 	return lit
-
 }
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	params := []*ast.Identifier{}
@@ -270,31 +404,67 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 }
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseCallArguments()
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.Rparen = p.curToken
 	return exp
 }
 
-// seem as parseExpressionList
+// parseCallArguments parses the arguments of a call like
+// parseExpressionList, along with ...spread and name: value arguments,
+// which come after the positional ones.
 func (p *Parser) parseCallArguments() []ast.Expression {
+	defer p.unguard()()
 	args := []ast.Expression{}
-	if p.peekTokenIs(token.RPAREN) {
+	keywords := map[string]bool{}
+	for !p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return args
+		var arg ast.Expression
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			spread := &ast.SpreadExpression{Token: p.curToken}
+			p.nextToken()
+			if spread.Value = p.parseExpression(LOWEST); spread.Value == nil {
+				return nil
+			}
+			arg = spread
+		case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+			keyword := &ast.KeywordArgument{Token: p.curToken, Name: p.curToken.Literal}
+			if keywords[keyword.Name] {
+				p.errors = append(p.errors, fmt.Sprintf("keyword argument %s repeated", keyword.Name))
+				return nil
+			}
+			keywords[keyword.Name] = true
+			p.nextToken()
+			p.nextToken()
+			if keyword.Value = p.parseExpression(LOWEST); keyword.Value == nil {
+				return nil
+			}
+			arg = keyword
+		default:
+			if arg = p.parseExpression(LOWEST); arg == nil {
+				return nil
+			}
+		}
+		if _, ok := arg.(*ast.KeywordArgument); !ok && len(keywords) > 0 {
+			p.errors = append(p.errors, fmt.Sprintf("argument %s follows keyword arguments", arg.String()))
+			return nil
+		}
+		args = append(args, arg)
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
 	}
-
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
-	}
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
 	return args
 }
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	if fn := p.parseArrowParameters(); fn != nil {
+		return p.parseArrowBody(fn)
+	}
+	defer p.unguard()()
 	p.nextToken()
 	// precedence of RPAREN is LOWEST ,so when peektoken slide to ( , any operator in the ( ...) has much more powerful right way
 	// constraints than the (
@@ -305,40 +475,502 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	}
 	return exp
 }
+
+// parseArrowParameters parses the parameters of (params) => body from the
+// current (, like those of fn. It returns nil, leaving the parser where it
+// was, if they are not followed by a =>.
+func (p *Parser) parseArrowParameters() *ast.FunctionLiteral {
+	if p.guard || !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.ELLIPSIS) && !p.peekTokenIs(token.RPAREN) {
+		return nil
+	}
+	lexer, cur, peek, errors := *p.l, p.curToken, p.peekToken, len(p.errors)
+	fn := &ast.FunctionLiteral{Token: arrowToken(p.curToken)}
+	if p.parseParameters(fn) && p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		return fn
+	}
+	*p.l, p.curToken, p.peekToken, p.errors = lexer, cur, peek, p.errors[:errors]
+	return nil
+}
+
+// parseArrowBody parses the body of fn after the =>: a block, or an
+// expression which is its value.
+func (p *Parser) parseArrowBody(fn *ast.FunctionLiteral) ast.Expression {
+	defer p.unguard()()
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		fn.Body = p.parseBlockStatement()
+		return fn
+	}
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
+	fn.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+	return fn
+}
+
+// arrowToken is the token of the function literal of an arrow function
+// starting at tok.
+func arrowToken(tok token.Token) token.Token {
+	return token.Token{Type: token.FUNCTION, Literal: "fn", Line: tok.Line, Column: tok.Column}
+}
+
+// unguard lifts p.guard while parsing inside brackets, where a => cannot
+// end a match guard; calling the result puts it back.
+func (p *Parser) unguard() func() {
+	guard := p.guard
+	p.guard = false
+	return func() { p.guard = guard }
+}
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+
+	parts, err := lexer.SplitTemplate(p.curToken.Literal)
+	if err != nil {
+		p.errors = append(p.errors, err.Error())
+		return nil
+	}
+	for _, part := range parts {
+		if !part.Expr {
+			tok := p.curToken
+			tok.Type, tok.Literal = token.STRING, part.Text
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: tok, Value: part.Text})
+			continue
+		}
+		exp := p.parseEmbedded(part.Text)
+		if exp == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, exp)
+	}
+	return str
+}
+
+// parseEmbedded parses the source of a ${} expression in a string.
+func (p *Parser) parseEmbedded(src string) ast.Expression {
+	sub := New(lexer.New(src))
+	if sub.curTokenIs(token.EOF) {
+		p.errors = append(p.errors, "empty ${} in string")
+		return nil
+	}
+	exp := sub.parseExpression(LOWEST)
+	if len(sub.Errors()) == 0 && !sub.peekTokenIs(token.EOF) {
+		sub.errors = append(sub.errors, fmt.Sprintf("unexpected %s after expression", sub.peekToken.Literal))
+	}
+	for _, msg := range sub.Errors() {
+		p.errors = append(p.errors, fmt.Sprintf("in ${%s}: %s", src, msg))
+	}
+	if len(sub.Errors()) != 0 {
+		return nil
+	}
+	return exp
+}
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{
 		Token: p.curToken,
 	}
-	if !p.expectPeek(token.LPAREN) {
+	if expression.Condition, expression.Consequence = p.parseIfClause(); expression.Consequence == nil {
 		return nil
 	}
+
+	for p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+
+		if !p.peekTokenIs(token.IF) {
+			if !p.expectPeek(token.LBRACE) {
+				return nil
+			}
+			expression.Alternative = p.parseBlockStatement()
+			break
+		}
+		p.nextToken()
+		elseIf := &ast.ElseIf{Token: p.curToken}
+		if elseIf.Condition, elseIf.Consequence = p.parseIfClause(); elseIf.Consequence == nil {
+			return nil
+		}
+		expression.ElseIfs = append(expression.ElseIfs, elseIf)
+	}
+	return expression
+}
+
+// parseIfClause parses the (condition) { consequence } following an if; the
+// consequence is nil if they are malformed.
+func (p *Parser) parseIfClause() (ast.Expression, *ast.BlockStatement) {
+	if !p.expectPeek(token.LPAREN) {
+		return nil, nil
+	}
 	p.nextToken()
-	expression.Condition = p.parseExpression(LOWEST)
+	condition := p.parseExpression(LOWEST)
 	//slide to ")" and stop
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return nil, nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil, nil
 	}
+	return condition, p.parseBlockStatement()
+}
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
-	expression.Consequence = p.parseBlockStatement()
+	expression.Block = p.parseBlockStatement()
 
-	if p.peekTokenIs(token.ELSE) {
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+	if p.peekTokenIs(token.FINALLY) {
 		p.nextToken()
-
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
-		expression.Alternative = p.parseBlockStatement()
+		expression.Finally = p.parseBlockStatement()
+	}
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errors = append(p.errors, "try needs a catch or a finally block")
+		return nil
+	}
+	return expression
+}
+func (p *Parser) parseYieldExpression() ast.Expression {
+	expression := &ast.YieldExpression{Token: p.curToken}
+	p.nextToken()
+	expression.Value = p.parseExpression(LOWEST)
+	return expression
+}
+
+// parseForExpression parses for (x in xs) { body }.
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	expression := &ast.SpawnExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp := p.parseExpression(PREFIX)
+	call, ok := exp.(*ast.CallExpression)
+	if !ok {
+		if exp != nil {
+			p.errors = append(p.errors, fmt.Sprintf("spawn needs a function call, got %s", exp.String()))
+		}
+		return nil
+	}
+	expression.Call = call
+	return expression
+}
+
+// parseSelectExpression parses select { case ... { } default { } }.
+func (p *Parser) parseSelectExpression() ast.Expression {
+	expression := &ast.SelectExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		switch {
+		case p.peekTokenIs(token.CASE):
+			p.nextToken()
+			c := p.parseSelectCase()
+			if c == nil {
+				return nil
+			}
+			expression.Cases = append(expression.Cases, c)
+		case p.peekTokenIs(token.DEFAULT):
+			p.nextToken()
+			if expression.Default != nil {
+				p.errors = append(p.errors, "select has more than one default")
+				return nil
+			}
+			if !p.expectPeek(token.LBRACE) {
+				return nil
+			}
+			expression.Default = p.parseBlockStatement()
+		default:
+			p.errors = append(p.errors, fmt.Sprintf("select expects case or default, got %s", p.peekToken.Literal))
+			return nil
+		}
+	}
+	p.nextToken()
+	expression.Rbrace = p.curToken
+	if len(expression.Cases) == 0 {
+		p.errors = append(p.errors, "select needs a case")
+		return nil
+	}
+	return expression
+}
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	c := &ast.SelectCase{Token: p.curToken}
+
+	p.nextToken()
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+		c.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.nextToken()
+		p.nextToken()
+	}
+	exp := p.parseExpression(LOWEST)
+	call, ok := exp.(*ast.CallExpression)
+	if ok {
+		c.Call = call
+		fn, _ := call.Function.(*ast.Identifier)
+		ok = fn != nil && (fn.Value == "recv" && len(call.Arguments) == 1 ||
+			fn.Value == "send" && len(call.Arguments) == 2 && c.Variable == nil)
+	}
+	if !ok {
+		if exp != nil {
+			p.errors = append(p.errors, fmt.Sprintf("select case must be recv(channel) or send(channel, value), got %s", exp.String()))
+		}
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	c.Body = p.parseBlockStatement()
+	return c
+}
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm, block := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+		// a comma separates arms, optional after a block
+		if p.peekTokenIs(token.COMMA) || !block && !p.peekTokenIs(token.RBRACE) {
+			if !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+	}
+	p.nextToken()
+	expression.Rbrace = p.curToken
+	if len(expression.Arms) == 0 {
+		p.errors = append(p.errors, "match needs an arm")
+		return nil
 	}
 	return expression
 }
+
+// parseMatchArm also reports whether the body of the arm is a block rather
+// than an expression.
+func (p *Parser) parseMatchArm() (*ast.MatchArm, bool) {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil, false
+	}
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		guard := p.guard
+		p.guard = true
+		arm.Guard = p.parseExpression(LOWEST)
+		p.guard = guard
+	}
+	if !p.expectPeek(token.ARROW) {
+		return nil, false
+	}
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm, true
+	}
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	arm.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+	return arm, false
+}
+
+// parsePattern parses the pattern starting at the current token and checks
+// that it binds no name twice.
+func (p *Parser) parsePattern() ast.Pattern {
+	pattern := p.parseSubPattern()
+	if pattern == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, name := range ast.Bindings(pattern) {
+		if seen[name.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("%s is bound more than once in %s", name.Value, pattern.String()))
+			return nil
+		}
+		seen[name.Value] = true
+	}
+	return pattern
+}
+func (p *Parser) parseSubPattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.peekTokenIs(token.LBRACE) {
+			p.errors = append(p.errors, fmt.Sprintf("struct pattern %s{...} is not supported: structs have no instances", p.curToken.Literal))
+			return nil
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		if literal := p.parseLiteralPattern(); literal != nil {
+			return literal
+		}
+		return nil
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	}
+	p.errors = append(p.errors, fmt.Sprintf("expected a pattern, got %s", p.curToken.Literal))
+	return nil
+}
+func (p *Parser) parseLiteralPattern() *ast.LiteralPattern {
+	pattern := &ast.LiteralPattern{Token: p.curToken}
+	if p.curTokenIs(token.MINUS) {
+		if !p.expectPeek(token.INT) {
+			return nil
+		}
+		right := p.parseIntegerLiteral()
+		if right == nil {
+			return nil
+		}
+		pattern.Value = &ast.PrefixExpression{Token: pattern.Token, Operator: "-", Right: right}
+		return pattern
+	}
+	pattern.Value = p.prefixParseFns[p.curToken.Type]()
+	if pattern.Value == nil {
+		return nil
+	}
+	return pattern
+}
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.DOTDOT) {
+			// .. alone stands for .._
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: "_"}
+			if p.peekTokenIs(token.IDENT) {
+				p.nextToken()
+				pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.errors = append(p.errors, fmt.Sprintf("..%s must come last in an array pattern", pattern.Rest.Value))
+				return nil
+			}
+			break
+		}
+		element := p.parseDefault(p.parseSubPattern())
+		if element == nil {
+			return nil
+		}
+		if _, ok := element.(*ast.DefaultPattern); !ok && len(pattern.Elements) > 0 {
+			if _, ok := pattern.Elements[len(pattern.Elements)-1].(*ast.DefaultPattern); ok {
+				p.errors = append(p.errors, fmt.Sprintf("%s must have a default, following one that has", element.String()))
+				return nil
+			}
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	pattern.Rbracket = p.curToken
+	return pattern
+}
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		var key ast.Expression
+		var value ast.Pattern
+		switch p.curToken.Type {
+		case token.IDENT:
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.COLON) {
+				value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+		case token.INT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+			literal := p.parseLiteralPattern()
+			if literal == nil {
+				return nil
+			}
+			key = literal.Value
+		default:
+			p.errors = append(p.errors, fmt.Sprintf("expected a key in a hash pattern, got %s", p.curToken.Literal))
+			return nil
+		}
+		if value == nil {
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			if value = p.parseSubPattern(); value == nil {
+				return nil
+			}
+		}
+		if value = p.parseDefault(value); value == nil {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	pattern.Rbrace = p.curToken
+	return pattern
+}
+// parseDefault parses the = default that may follow pattern, an element of
+// an array or hash pattern.
+func (p *Parser) parseDefault(pattern ast.Pattern) ast.Pattern {
+	if pattern == nil || !p.peekTokenIs(token.ASSIGN) {
+		return pattern
+	}
+	p.nextToken()
+	dflt := &ast.DefaultPattern{Token: p.curToken, Pattern: pattern}
+	p.nextToken()
+	if dflt.Default = p.parseExpression(LOWEST); dflt.Default == nil {
+		return nil
+	}
+	return dflt
+}
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -355,24 +987,122 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// right associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 	return expression
 }
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+	optional := tok.Type == token.QLBRACKET
+	if p.peekTokenIs(token.COLON) {
+		if optional {
+			p.errors = append(p.errors, fmt.Sprintf("cannot slice %s with ?[", left.String()))
+			return nil
+		}
+		return p.parseSliceExpression(tok, left, index)
+	}
+	exp := &ast.IndexExpression{Token: tok, Left: left, Index: index, Optional: optional}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Rbracket = p.curToken
+
+	return exp
+}
 
+// parseSliceExpression parses the rest of left[start:end:step] from the
+// first colon.
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
 
+	exp.End = p.parseSliceBound()
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		exp.Step = p.parseSliceBound()
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	exp.Rbracket = p.curToken
+
+	return exp
+}
+
+// parseSliceBound parses an optional bound after a colon.
+func (p *Parser) parseSliceBound() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpression(LOWEST)
+}
+func (p *Parser) parseSelectorExpression(left ast.Expression) ast.Expression {
+	exp := &ast.SelectorExpression{Token: p.curToken, Left: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+// parseOptionalSelector parses left?.name.
+func (p *Parser) parseOptionalSelector(left ast.Expression) ast.Expression {
+	exp := p.parseSelectorExpression(left)
+	if exp == nil {
+		return nil
+	}
+	exp.(*ast.SelectorExpression).Optional = true
+	return exp
+}
 
+// parsePipeline parses left |> f(args), which is f(left, args); a right side
+// that is not a call is called with left alone.
+func (p *Parser) parsePipeline(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	p.nextToken()
+	right := p.parseExpression(PIPELINE)
+	if right == nil {
+		return nil
+	}
+	if call, ok := right.(*ast.CallExpression); ok {
+		args := append([]ast.Expression{left}, call.Arguments...)
+		return &ast.CallExpression{Token: call.Token, Function: call.Function, Arguments: args, Rparen: call.Rparen}
+	}
+	return &ast.CallExpression{Token: tok, Function: right, Arguments: []ast.Expression{left}}
+}
+
+// parseConditionalExpression parses the rest of condition ? a : b, which
+// is right associative.
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+	p.nextToken()
+
+	exp.Consequence = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	exp.Alternative = p.parseExpression(TERNARY - 1)
+	if exp.Consequence == nil || exp.Alternative == nil {
+		return nil
+	}
 	return exp
 }
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Const: p.curTokenIs(token.CONST)}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -394,6 +1124,94 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 	return stmt
 }
+// parseLetPatternStatement parses let [a, b] = v; and let {a, b: c} = v;.
+func (p *Parser) parseLetPatternStatement() ast.Statement {
+	stmt := &ast.LetPatternStatement{Token: p.curToken}
+	p.nextToken()
+	if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+		return nil
+	}
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseImportStatement parses `import "path" as name;` and
+// `import { a, b } from "path";`. `as` and `from` are not keywords.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		for !p.peekTokenIs(token.RBRACE) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+		if len(stmt.Names) == 0 {
+			p.errors = append(p.errors, "import list must name at least one binding")
+			return nil
+		}
+		if !p.expectContextual("from") || !p.expectPeek(token.STRING) {
+			return nil
+		}
+		stmt.Path = p.curToken.Literal
+		stmt.PathToken = p.curToken
+	} else {
+		if !p.expectPeek(token.STRING) {
+			return nil
+		}
+		stmt.Path = p.curToken.Literal
+		stmt.PathToken = p.curToken
+		if !p.expectContextual("as") || !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// expectContextual advances over an identifier that acts as a keyword only
+// in one place, such as `as` and `from` in imports.
+func (p *Parser) expectContextual(word string) bool {
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == word {
+		p.nextToken()
+		return true
+	}
+	p.errors = append(p.errors, fmt.Sprintf("expected next token to be %q, got %s instead", word, p.peekToken.Literal))
+	return false
+}
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.CONST) {
+		p.nextToken()
+	} else if !p.expectPeek(token.LET) {
+		return nil
+	}
+	let := p.parseLetStatement()
+	if let == nil {
+		return nil
+	}
+	stmt.Statement = let
+	return stmt
+}
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -406,6 +1224,34 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	return stmt
 }
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+func (p *Parser) parseDeferStatement() ast.Statement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	call, ok := exp.(*ast.CallExpression)
+	if !ok {
+		if exp != nil {
+			p.errors = append(p.errors, fmt.Sprintf("defer needs a function call, got %s", exp.String()))
+		}
+		return nil
+	}
+	stmt.Call = call
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
 func (p *Parser) parseStructDeclarionStatement() *ast.StructDeclarion {
 	stmt := &ast.StructDeclarion{Token: p.curToken}
 
@@ -415,6 +1261,7 @@ func (p *Parser) parseStructDeclarionStatement() *ast.StructDeclarion {
 		return nil
 	}
 	stmts := p.parseBlockStatement().Statements
+	stmt.Rbrace = p.curToken
 	methods := make([]*ast.FunctionLiteral, 0)
 	vars := make([]*ast.Identifier, 0)
 	for _, member := range stmts {
@@ -442,10 +1289,9 @@ func (p *Parser) parseFunctionDeclarionStatement() *ast.FunctionDeclarionStateme
 	p.nextToken()
 	stmt.Name = p.parseExpression(NAMEDECLARION).TokenLiteral()
 	fn.Name = stmt.Name
-	if !p.expectPeek(token.LPAREN) {
+	if !p.expectPeek(token.LPAREN) || !p.parseParameters(fn) {
 		return nil
 	}
-	fn.Parameters = p.parseFunctionParameters()
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -480,10 +1326,14 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+	}
 	return block
 }
+// Errors returns the errors of the lexer followed by those of the parser.
 func (p *Parser) Errors() []string {
-	return p.errors
+	return append(append([]string{}, p.l.Errors()...), p.errors...)
 }
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
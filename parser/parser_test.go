@@ -303,3 +303,557 @@ func TestFunctionDeclarion(t *testing.T) {
 	}
 
 }
+
+func TestImportExport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math.gw" as math;`, `import "lib/math.gw" as math;`},
+		{`import { add, sub } from "math.gw"`, `import {add, sub} from "math.gw";`},
+		{`export let x = 1;`, `export let x = 1;`},
+		{`math.add(1, 2)`, `(math.add)(1,2)`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{`import "x.gw" math;`, `import {} from "x.gw";`, `export 1;`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`defer close(f);`, `defer close(f);`},
+		{`defer puts("done", x + 1)`, `defer puts(done,(x + 1));`},
+		{`defer fn() { cleanup() }();`, `defer fn()cleanup()();`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{`defer x;`, `defer 1 + f();`, `defer;`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`s[1:2]`, `(s[1:2])`},
+		{`s[:n + 1]`, `(s[:(n + 1)])`},
+		{`s[1:]`, `(s[1:])`},
+		{`s[:]`, `(s[:])`},
+		{`s[i]`, `(s[i])`},
+		{`s[::2]`, `(s[::2])`},
+		{`s[1::-1]`, `(s[1::(-1)])`},
+		{`s[a:b:c]`, `(s[a:b:c])`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{`s[]`, `s[1:2`, `s[1:2:3:4]`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	p := New(lexer.New(`"hello ${name}, ${len(items) + 1} items"`))
+	pg := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors %v", p.Errors())
+	}
+	str, ok := pg.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("expected InterpolatedString,got %T", pg.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+	if len(str.Parts) != 5 {
+		t.Fatalf("expected 5 parts,got %d", len(str.Parts))
+	}
+	if got := str.String(); got != "hello ${name}, ${(len(items) + 1)} items" {
+		t.Fatalf("wrong String() %q", got)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"${}"`, "empty ${} in string"},
+		{`"${1 +}"`, "in ${1 +}: no prefix parse fn for EOF"},
+		{`"${a b}"`, "in ${a b}: unexpected b after expression"},
+		{`"\z"`, `1: invalid escape sequence \z`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) != 1 || errs[0] != tt.expected {
+			t.Errorf("test %v :expected [%s],got %v", i, tt.expected, errs)
+		}
+	}
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`a || b && c`, `(a || (b && c))`},
+		{`a && b == c`, `(a && (b == c))`},
+		{`a <= b == c >= d`, `((a <= b) == (c >= d))`},
+		{`a + b % c`, `(a + (b % c))`},
+		{`a | b & c ^ d`, `((a | (b & c)) ^ d)`},
+		{`a << 1 + b`, `((a << 1) + b)`},
+		{`a < b << 2`, `(a < (b << 2))`},
+		{`2 ** 3 ** 2`, `(2 ** (3 ** 2))`},
+		{`-2 ** 2`, `(-(2 ** 2))`},
+		{`2 ** -1`, `(2 ** (-1))`},
+		{`a * b ** c`, `(a * (b ** c))`},
+		{`~a & b`, `((~a) & b)`},
+		{`f(x) ** a[0]`, `(f(x) ** (a[0]))`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+}
+
+func TestTryThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { f() } catch (e) { e }`, `try f() catch (e) e`},
+		{`try { f() } finally { g() }`, `try f() finally g()`},
+		{`try { a } catch (e) { b } finally { c }`, `try a catch (e) b finally c`},
+		{`let x = 1 + try { a } catch (err) { 0 };`, `let x = (1 + try a catch (err) 0);`},
+		{`throw "boom";`, `throw boom;`},
+		{`fn() { throw x + 1 }`, `fn()throw (x + 1);`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{`try { a }`, `try { a } catch { b }`, `try { a } catch (1) { b }`, `try a catch (e) { b }`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestYieldFor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn() { yield 1 + 2 }`, `fn()yield (1 + 2)`},
+		{`fn(xs) { for (x in xs) { yield x * x } }`, `fn(xs)for (x in xs) yield (x * x)`},
+		{`for (c in "abc") { puts(c); }`, `for (c in abc) puts(c)`},
+		{`let n = for (x in f(1)) { x };`, `let n = for (x in f(1)) x;`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{`for x in xs { x }`, `for (1 in xs) { x }`, `for (x xs) { x }`, `for (x in xs) x`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestSpawnSelect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`spawn f(1, x + 1);`, `spawn f(1,(x + 1))`},
+		{`let t = spawn fn(c) { send(c, 1) }(ch);`, `let t = spawn fn(c)send(c,1)(ch);`},
+		{`select { case v = recv(c) { v } }`, `select { case v = recv(c) v }`},
+		{`select { case recv(a) { 1 } case send(b, 2) { 2 } default { 3 } }`,
+			`select { case recv(a) 1 case send(b,2) 2 default 3 }`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{
+		`spawn f;`,
+		`select { }`,
+		`select { default { 1 } }`,
+		`select { case recv(a) { 1 } default { 2 } default { 3 } }`,
+		`select { case f(a) { 1 } }`,
+		`select { case v = send(a, 1) { 1 } }`,
+		`select { case recv(a, b) { 1 } }`,
+		`select { recv(a) }`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { 1 => "one", -2 => "minus two", _ => "other" }`, `match (x) { 1 => one, (-2) => minus two, _ => other }`},
+		{`match (p) { [a, b, ..rest] if a > b => rest, [] => 0 }`, `match (p) { [a, b, ..rest] if (a > b) => rest, [] => 0 }`},
+		{`match (u) { {"type": "user", "id": id} => id, {name, age: years} => years }`,
+			`match (u) { {type: user, id: id} => id, {name: name, age: years} => years }`},
+		{`match (v) { [[x], {1: true}] => { puts(x); x } [..] => 0 }`, `match (v) { [[x], {1: true}] => puts(x)x, [.._] => 0 }`},
+		{`let n = match (f(1)) { x => x + 1, };`, `let n = match (f(1)) { x => (x + 1) };`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{
+		`match (x) { }`,
+		`match x { 1 => 1 }`,
+		`match (x) { 1 => 1 2 => 2 }`,
+		`match (x) { 1 => }`,
+		`match (x) { f(1) => 1 }`,
+		`match (x) { [..rest, a] => 1 }`,
+		`match (x) { [a, a] => 1 }`,
+		`match (x) { {a, "a": a} => 1 }`,
+		`match (x) { {f(): a} => 1 }`,
+		`match (x) { Point{x, y} => 1 }`,
+		`match (x) { "${x}" => 1 }`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+func TestLetPattern(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b, ..rest] = arr;`, `let [a, b, ..rest] = arr;`},
+		{`let {name, age: years} = person`, `let {name: name, age: years} = person;`},
+		{`let [x, y = 0] = pt;`, `let [x, y = 0] = pt;`},
+		{`let {p: [x, _], q = [1]} = f(1);`, `let {p: [x, _], q: q = [1]} = f(1);`},
+		{`match (v) { [a, b = a + 1] => b }`, `match (v) { [a, b = (a + 1)] => b }`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{
+		`let [a, a] = x;`,
+		`let [a = 1, b] = x;`,
+		`let [a] x;`,
+		`let {f(): a} = x;`,
+		`let [a = ] = x;`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+func TestParametersAndArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(x, y = 10) { x + y }`, `fn(x,y = 10)(x + y)`},
+		{`fn(first, ...rest) { rest }`, `fn(first,...rest)rest`},
+		{`fn(a, b = a * 2, ...c) { c }`, `fn(a,b = (a * 2),...c)c`},
+		{`f(...arr)`, `f(...arr)`},
+		{`f(1, ...xs, 2)`, `f(1,...xs,2)`},
+		{`f(y: 2, x: 1)`, `f(y: 2,x: 1)`},
+		{`f(a, ...b, k: c + 1)`, `f(a,...b,k: (c + 1))`},
+		{`fn g(x = 1) { x }`, `this is a function declarionfn<g>(x = 1)x`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{
+		`fn(x = 1, y) { y }`,
+		`fn(...r, x) { x }`,
+		`fn(...r = []) { r }`,
+		`fn(x, x) { x }`,
+		`f(x: 1, 2)`,
+		`f(x: 1, ...xs)`,
+		`f(x: 1, x: 2)`,
+		`f(1 2)`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`if (a) { 1 } else if (b) { 2 } else { 3 }`, `ifa 1else ifb 2else 3`},
+		{`if (a) { 1 } else if (b) { 2 } else if (c) { 3 }`, `ifa 1else ifb 2else ifc 3`},
+		{`a ? b : c`, `(a ? b : c)`},
+		{`a ? b : c ? d : e`, `(a ? b : (c ? d : e))`},
+		{`a || b ? c + 1 : d`, `((a || b) ? (c + 1) : d)`},
+		{`{"k": a ? b : c}`, `{k:(a ? b : c)}`},
+		{`a ?? b ?? c`, `((a ?? b) ?? c)`},
+		{`a ?? b || c`, `(a ?? (b || c))`},
+		{`a ?? b ? c : d`, `((a ?? b) ? c : d)`},
+		{`a?.b?["c"]`, `((a?.b)?[c])`},
+		{`a?.b + 1`, `((a?.b) + 1)`},
+		{`a?.b.c(1)`, `((a?.b).c)(1)`},
+		{`f(x)?[0]`, `(f(x)?[0])`},
+		{`a ? [1] : [2]`, `(a ? [1] : [2])`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{
+		`if (a) { 1 } else if { 2 }`,
+		`if (a) { 1 } else if (b) 2`,
+		`a ? b`,
+		`a ? b : `,
+		`a?.1`,
+		`a?[1:2]`,
+		`a ?[1] : [2]`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestPipelineAndArrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`xs |> map(f) |> filter(g)`, `filter(map(xs,f),g)`},
+		{`xs |> len`, `len(xs)`},
+		{`a + b |> f(c) == 3`, `(f((a + b),c) == 3)`},
+		{`xs |> f(...ys, k: 1)`, `f(xs,...ys,k: 1)`},
+		{`x => x * 2`, `fn(x)(x * 2)`},
+		{`(x) => x * 2`, `fn(x)(x * 2)`},
+		{`(x, y = 1, ...r) => { x + y }`, `fn(x,y = 1,...r)(x + y)`},
+		{`() => 1`, `fn()1`},
+		{`let double = x => x * 2;`, `let double = fn<double>(x)(x * 2);`},
+		{`map(xs, x => x + 1)`, `map(xs,fn(x)(x + 1))`},
+		{`x => y => x + y`, `fn(x)fn(y)(x + y)`},
+		{`xs |> map(x => x * 2)`, `map(xs,fn(x)(x * 2))`},
+		{`(a + b) * c`, `((a + b) * c)`},
+		{`(a)`, `a`},
+		{`match (v) { x if a > y => y, _ => z => z }`, `match (v) { x if (a > y) => y, _ => fn(z)z }`},
+		{`match (v) { x if (a) => 1, x if any(xs, y => y) => 2 }`, `match (v) { x if a => 1, x if any(xs,fn(y)y) => 2 }`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+	}
+
+	for _, input := range []string{
+		`xs |> `,
+		`(x, 1) => x`,
+		`(x, x) => x`,
+		`(x) => `,
+		`(a, b)`,
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const limit = 10;`, `const limit = 10;`},
+		{`const f = x => x;`, `const f = fn<f>(x)x;`},
+		{`export const scale = 2;`, `export const scale = 2;`},
+	}
+	for i, tt := range tests {
+		p := New(lexer.New(tt.input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		if pg.String() != tt.expected {
+			t.Fatalf("test %v :expected %v,got %v", i, tt.expected, pg.String())
+		}
+		if let, ok := pg.Statements[0].(*ast.LetStatement); ok && !let.Const {
+			t.Fatalf("test %v :expected a const", i)
+		}
+	}
+
+	for _, input := range []string{`const = 1;`, `const x;`, `const [a] = [1];`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parse errors", input)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	inputs := []string{
+		`f(1, 2)`,
+		`[1, 2][0]`,
+		`{"a": 1}`,
+		`a[1:2]`,
+		`"x\ty"`,
+		`"a${b}c"`,
+		`-x + y * 2`,
+		`match (x) { [a, ..] => a, {b} => b }`,
+		`if (a) { b } else if (c) { d }`,
+		`try { 1 } catch (e) { 2 }`,
+		`xs |> f`,
+		`x => x + 1`,
+		`h?.name`,
+		`for (x in xs) { x }`,
+	}
+	for i, input := range inputs {
+		p := New(lexer.New(input))
+		pg := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("test %v :parse errors %v", i, p.Errors())
+		}
+		exp := pg.Statements[0].(*ast.ExpressionStatement).Expression
+		start, end := ast.StartToken(exp), ast.EndToken(exp)
+		if start.Line != 1 || start.Column != 1 || end.EndLine != 1 || end.EndColumn != len(input)+1 {
+			t.Errorf("test %v :%s spans %v:%v-%v:%v", i, input, start.Line, start.Column, end.EndLine, end.EndColumn)
+		}
+
+		data, err := ast.Marshal(pg)
+		if err != nil {
+			t.Fatalf("test %v :marshal fail %v", i, err)
+		}
+		decoded, err := ast.Unmarshal(data)
+		if err != nil {
+			t.Fatalf("test %v :unmarshal fail %v", i, err)
+		}
+		if again, _ := ast.Marshal(decoded); string(again) != string(data) {
+			t.Errorf("test %v :second encoding differs\n%s\n%s", i, data, again)
+		}
+	}
+}
@@ -1,45 +1,132 @@
 package repl
 
 import (
+	"errors"
 	"fmt"
+	"gwine/ast"
 	"gwine/compiler"
+	"gwine/evaluator"
 	"gwine/lexer"
+	"gwine/module"
 	"gwine/object"
 	"gwine/parser"
 	"gwine/vm"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func FromFile(file string) {
 
-	f , err := ioutil.ReadFile(file)
-	if err != nil{
+	f, err := ioutil.ReadFile(file)
+	if err != nil {
 		fmt.Println(err)
 	}
-	constants := []object.Object{}
-	globals := make([]object.Object, vm.GlobalsSize)
-	symboltbl := compiler.NewSymbolTable()
 
 	l := lexer.New(string(f))
 	p := parser.New(l)
 	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Fprintln(os.Stdout, strings.Join(p.Errors(), "\n"))
+		return
+	}
 	//fmt.Fprintln(out,program.String())
 
+	err = RunProgram(program, file, NewLoader(), os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// NewLoader returns a module loader that searches the given directories and
+// expands the macros of every module it loads.
+func NewLoader(searchPath ...string) *module.Loader {
+	loader := module.NewLoader(searchPath...)
+	loader.Transform = func(program *ast.Program) (*ast.Program, error) {
+		return expand(program, object.NewEnvironment())
+	}
+	return loader
+}
+
+// enterFile marks file as being loaded, so that modules importing it back
+// are reported as a cycle. It returns the absolute path of file, or "".
+func enterFile(loader *module.Loader, file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	return abs, loader.Begin(abs)
+}
+
+// RunProgram expands macros in program, compiles it, runs it on the vm and
+// writes the last popped value to out, which is also the program's output.
+// Imports are resolved with loader, relative to file, which may be empty.
+func RunProgram(program *ast.Program, file string, loader *module.Loader, out io.Writer) error {
+	program, err := expand(program, object.NewEnvironment())
+	if err != nil {
+		return err
+	}
+	file, err = enterFile(loader, file)
+	if err != nil {
+		return err
+	}
+	constants := []object.Object{}
+	globals := vm.NewGlobals()
+	symboltbl := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symboltbl.DefineBuiltin(i, v.Name)
+	}
+
 	comp := compiler.NewWithState(symboltbl, constants)
+	comp.SetLoader(loader, file)
 	err = comp.Compile(program)
 	if err != nil {
-		fmt.Fprintf(os.Stdout, "compile fail")
+		return fmt.Errorf("compile fail: %v", err)
 	}
 	code := comp.ByteCode()
-	constants = code.Constants
 	vmm := vm.NewWithGlobalStore(code, globals)
+	vmm.SetStreams(os.Stdin, out)
 	err = vmm.Run()
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 	// st := vm.Top()
 	// io.WriteString(out,st.Inspect() + "\n")
-	io.WriteString(os.Stdout, vmm.LastPoped().Inspect()+"\n")
+	if last := vmm.LastPoped(); last != nil {
+		io.WriteString(out, last.Inspect()+"\n")
+	}
+	return nil
+}
+
+// EvalProgram expands macros in program, runs it on the tree-walking
+// evaluator and writes the result to out, or returns the error it raised
+// like RunProgram does. Imports are resolved as in RunProgram.
+func EvalProgram(program *ast.Program, file string, loader *module.Loader, out io.Writer) error {
+	program, err := expand(program, object.NewEnvironment())
+	if err != nil {
+		return err
+	}
+	file, err = enterFile(loader, file)
+	if err != nil {
+		return err
+	}
+	env := object.NewEnvironment()
+	env.SetModules(object.NewModules(loader), file)
+	env.SetStreams(os.Stdin, out)
+	evaluated := evaluator.Eval(program, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.Exception != nil {
+			return err.Exception
+		}
+		return errors.New(err.Message)
+	}
+	if evaluated != nil {
+		io.WriteString(out, evaluated.Inspect()+"\n")
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package repl
 import (
 	"bufio"
 	"fmt"
+	"gwine/ast"
 	"gwine/compiler"
 	"gwine/evaluator"
 	"gwine/lexer"
@@ -16,6 +17,9 @@ func StartForInterpreter(in io.Reader, out io.Writer) {
 
 	sc := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	env.SetModules(object.NewModules(NewLoader()), "")
+	env.SetStreams(&lineReader{sc: sc}, out)
+	macroEnv := object.NewEnvironment()
 
 	for {
 
@@ -30,6 +34,11 @@ func StartForInterpreter(in io.Reader, out io.Writer) {
 		program := p.ParseProgram()
 		//fmt.Fprintln(out,program.String())
 
+		program, err := expand(program, macroEnv)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
 		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
@@ -42,11 +51,14 @@ func StartForVm(in io.Reader, out io.Writer) {
 	sc := bufio.NewScanner(in)
 
 	constants := []object.Object{}
-	globals := make([]object.Object, vm.GlobalsSize)
+	globals := vm.NewGlobals()
 	symboltbl := compiler.NewSymbolTable()
 	for i, v := range object.Builtins {
 		symboltbl.DefineBuiltin(i, v.Name)
 	}
+	macroEnv := object.NewEnvironment()
+	loader := NewLoader()
+	streams := object.NewStreams(&lineReader{sc: sc}, out)
 
 	for {
 
@@ -61,8 +73,14 @@ func StartForVm(in io.Reader, out io.Writer) {
 		program := p.ParseProgram()
 		//fmt.Fprintln(out,program.String())
 
+		program, err := expand(program, macroEnv)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
 		comp := compiler.NewWithState(symboltbl, constants)
-		err := comp.Compile(program)
+		comp.SetLoader(loader, "")
+		err = comp.Compile(program)
 		if err != nil {
 			fmt.Fprintf(out, "compile fail")
 			continue
@@ -70,6 +88,7 @@ func StartForVm(in io.Reader, out io.Writer) {
 		code := comp.ByteCode()
 		constants = code.Constants
 		vmm := vm.NewWithGlobalStore(code, globals)
+		vmm.SetStreams(streams.In, streams.Out)
 		err = vmm.Run()
 		if err != nil {
 			fmt.Println(err)
@@ -79,3 +98,33 @@ func StartForVm(in io.Reader, out io.Writer) {
 		io.WriteString(out, vmm.LastPoped().Inspect()+"\n")
 	}
 }
+
+// lineReader lets a program typed into the repl read the lines typed after
+// it, from the scanner the repl reads its input with.
+type lineReader struct {
+	sc   *bufio.Scanner
+	line []byte
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if len(r.line) == 0 {
+		if !r.sc.Scan() {
+			return 0, io.EOF
+		}
+		r.line = append([]byte(r.sc.Text()), '\n')
+	}
+	n := copy(p, r.line)
+	r.line = r.line[n:]
+	return n, nil
+}
+
+// expand runs the macro expansion phase: it takes the macro definitions out of
+// program and returns it with every macro call expanded.
+func expand(program *ast.Program, macroEnv *object.Environment) (*ast.Program, error) {
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(*ast.Program), nil
+}
@@ -17,14 +17,37 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+	POWER    = "**"
 
 	LT = "<"
 	GT = ">"
+	LE = "<="
+	GE = ">="
+
+	AND = "&&"
+	OR  = "||"
+
+	AMPERSAND = "&"
+	PIPE      = "|"
+	PIPELINE  = "|>"
+	CARET     = "^"
+	TILDE     = "~"
+	SHL       = "<<"
+	SHR       = ">>"
 
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
 	DOT       = "."
+	DOTDOT    = ".."
+	ELLIPSIS  = "..."
+	ARROW     = "=>"
+
+	QUESTION  = "?"
+	NULLISH   = "??"
+	QDOT      = "?."
+	QLBRACKET = "?["
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -34,15 +57,33 @@ const (
 	RBRACKET = "]"
 
 	FUNCTION = "FUNCTION"
+	MACRO    = "MACRO"
 	STRUCT   = "STRUCT"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	DEFER    = "DEFER"
+	YIELD    = "YIELD"
+	FOR      = "FOR"
+	IN       = "IN"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
+	MATCH    = "MATCH"
 
-	STRING = "STRING"
+	STRING   = "STRING"
+	TEMPLATE = "TEMPLATE" // a string with ${} interpolations, the literal is its source
 )
 
 type TokenType string
@@ -50,17 +91,49 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int
+	// EndLine and EndColumn locate the character just past the token. They
+	// are not Column + len(Literal) for strings, whose literal is unescaped.
+	EndLine   int
+	EndColumn int
+}
+
+// Comment is a `//` comment skipped by the lexer, kept so tools can read
+// directives such as `// lint:ignore`.
+type Comment struct {
+	Line     int
+	Text     string
+	Trailing bool // code comes before it on its line
 }
 
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
+	"macro":  MACRO,
 	"let":    LET,
+	"const":  CONST,
 	"true":   TRUE,
 	"false":  FALSE,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
 	"struct": STRUCT,
+	"import": IMPORT,
+	"export": EXPORT,
+
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+	"defer":   DEFER,
+	"yield":   YIELD,
+	"for":     FOR,
+	"in":      IN,
+	"spawn":   SPAWN,
+	"select":  SELECT,
+	"case":    CASE,
+	"default": DEFAULT,
+	"match":   MATCH,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"gwine/object"
+	"sync"
+	"sync/atomic"
+)
+
+// spawn returns the task calling fn with args on a vm of its own, sharing
+// the globals of this one; object/concurrency.go tells what tasks share.
+func (vm *VM) spawn(fn object.Object, args []object.Object) *object.Task {
+	atomic.StoreUint32(&vm.globals.spawned, 1)
+	t := vm.child()
+	t.stack[0] = fn
+	copy(t.stack[1:], args)
+	t.sp = 1 + len(args)
+
+	task := object.NewTask(vm.globals.tasks)
+	go func() {
+		err := t.call(len(args))
+		if err == nil {
+			err = t.Run()
+		}
+		if err != nil {
+			exception := t.exception(err)
+			task.Finish(&object.Error{Message: exception.Error(), Exception: exception})
+			return
+		}
+		task.Finish(t.stack[t.sp-1])
+	}()
+	return task
+}
+
+// Globals are the global variables of a program, which the vm running its
+// top level shares with the vms of the tasks it spawns, along with the
+// state of those tasks.
+type Globals struct {
+	values []object.Object
+	tasks  *object.Tasks
+
+	// mu guards values, which the top level may redefine while the tasks it
+	// spawned read them. Until a task is spawned, set before the first one
+	// starts, the vm running the top level is the only one to use them.
+	mu      sync.RWMutex
+	spawned uint32
+}
+
+// NewGlobals returns the globals of a program about to run, none set yet.
+func NewGlobals() *Globals {
+	return &Globals{values: make([]object.Object, GlobalsSize), tasks: object.NewTasks()}
+}
+
+func (vm *VM) getGlobal(index uint16) object.Object {
+	g := vm.globals
+	if atomic.LoadUint32(&g.spawned) == 0 {
+		return g.values[index]
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.values[index]
+}
+
+func (vm *VM) setGlobal(index uint16, value object.Object) {
+	g := vm.globals
+	if atomic.LoadUint32(&g.spawned) == 0 {
+		g.values[index] = value
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[index] = value
+}
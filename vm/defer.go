@@ -0,0 +1,52 @@
+package vm
+
+import "gwine/object"
+
+// deferredCall is a call deferred by OpDefer until its frame exits.
+type deferredCall struct {
+	fn   object.Object
+	args []object.Object
+}
+
+// leave exits the current frame, once its tries are done with, the way
+// exit says. It makes the deferred calls of the frame first, the last one
+// first: a builtin runs at once, a closure gets a frame marked deferred,
+// which goes on leaving this one when it returns. An error raised by a
+// deferred call replaces exit and unwinds from this frame, so the calls
+// left still run.
+func (vm *VM) leave(exit *unwinding) error {
+	for {
+		frame := vm.currentFrame()
+		n := len(frame.defers)
+		if n == 0 {
+			break
+		}
+		d := frame.defers[n-1]
+		frame.defers = frame.defers[:n-1]
+		frame.exit = exit
+
+		for _, obj := range append([]object.Object{d.fn}, d.args...) {
+			if err := vm.push(obj); err != nil {
+				return err
+			}
+		}
+		frames := vm.frameIndex
+		if err := vm.call(len(d.args)); err != nil {
+			return err
+		}
+		if vm.frameIndex > frames {
+			vm.currentFrame().deferred = true
+			return nil
+		}
+		vm.pop()
+	}
+	if exit.exception != nil {
+		return exit.exception
+	}
+	frame := vm.popFrame()
+	vm.sp = frame.basePointer - 1
+	if frame.deferred {
+		return vm.leave(vm.currentFrame().exit)
+	}
+	return vm.push(exit.returnValue)
+}
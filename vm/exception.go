@@ -0,0 +1,111 @@
+package vm
+
+import "gwine/object"
+
+// tryBlock is a try a frame has entered with OpTry.
+type tryBlock struct {
+	handler object.Handler
+	sp      int  // stack pointer at OpTry, restored when unwinding to it
+	caught  bool // its catch block is running, only the finally block is left
+}
+
+// unwinding is on top of the stack while a finally block runs because of a
+// throw or a return, which OpEndFinally goes on with.
+type unwinding struct {
+	exception   *object.Exception // nil when returning
+	returnValue object.Object
+}
+
+func (u *unwinding) Type() object.ObjectType { return "UNWINDING" }
+func (u *unwinding) Inspect() string {
+	if u.exception != nil {
+		return "unwinding(" + u.exception.Message + ")"
+	}
+	return "unwinding(return)"
+}
+
+// throw unwinds frames up to the innermost try handling exception, and
+// enters its catch block with the exception pushed or else its finally
+// block, which throws the exception again when it ends. A frame with
+// deferred calls on the way makes them first, see leave. It returns the
+// exception if nothing handles it, or the one a deferred call raised
+// instead.
+func (vm *VM) throw(exception *object.Exception) error {
+	for i := vm.frameIndex - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		for len(frame.tries) > 0 {
+			try := frame.tries[len(frame.tries)-1]
+			frame.tries = frame.tries[:len(frame.tries)-1]
+			switch {
+			case !try.caught && try.handler.Catch >= 0:
+				if try.handler.Finally >= 0 {
+					try.caught = true
+					frame.tries = append(frame.tries, try)
+				}
+				vm.unwindTo(i, try.sp, try.handler.Catch)
+				vm.stack[vm.sp] = exception
+				vm.sp++
+				return nil
+			case try.handler.Finally >= 0:
+				vm.unwindTo(i, try.sp, try.handler.Finally)
+				vm.stack[vm.sp] = &unwinding{exception: exception}
+				vm.sp++
+				return nil
+			}
+		}
+		if len(frame.defers) > 0 {
+			vm.frameIndex = i + 1
+			vm.sp = frame.basePointer + frame.cl.Fn.NumLocals
+			if err := vm.leave(&unwinding{exception: exception}); err != nil {
+				return vm.throw(vm.exception(err))
+			}
+			return nil
+		}
+	}
+	return exception
+}
+
+// exception returns the exception raised by err.
+func (vm *VM) exception(err error) *object.Exception {
+	if exception, ok := err.(*object.Exception); ok {
+		return exception
+	}
+	return &object.Exception{Message: err.Error(), Trace: vm.trace()}
+}
+
+// unwindTo makes frame i the current one, running from ip with the stack
+// pointer at sp.
+func (vm *VM) unwindTo(i, sp, ip int) {
+	vm.frameIndex = i + 1
+	vm.sp = sp
+	vm.frames[i].ip = ip - 1
+}
+
+// returnValue returns rv from the current frame, running the finally blocks
+// of the tries it is in and then its deferred calls first.
+func (vm *VM) returnValue(rv object.Object) error {
+	frame := vm.currentFrame()
+	for len(frame.tries) > 0 {
+		try := frame.tries[len(frame.tries)-1]
+		frame.tries = frame.tries[:len(frame.tries)-1]
+		if try.handler.Finally >= 0 {
+			vm.sp = try.sp
+			frame.ip = try.handler.Finally - 1
+			return vm.push(&unwinding{returnValue: rv})
+		}
+	}
+	return vm.leave(&unwinding{returnValue: rv})
+}
+
+// trace returns the names of the functions being called, innermost first;
+// those of a generator are followed by the trace of where it was made.
+func (vm *VM) trace() []string {
+	trace := []string{}
+	for i := vm.frameIndex - 1; i > 0; i-- {
+		trace = append(trace, object.TraceName(vm.frames[i].cl.Fn.Name))
+	}
+	if vm.caller != nil {
+		return append(trace, vm.caller...)
+	}
+	return append(trace, object.TraceMain)
+}
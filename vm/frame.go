@@ -9,6 +9,11 @@ type Frame struct {
 	cl          *object.Closure
 	ip          int
 	basePointer int
+	tries       []tryBlock // entered and not left, innermost last
+
+	defers   []deferredCall // in the order they were deferred
+	exit     *unwinding     // the return or throw leaving the frame while its defers run
+	deferred bool           // the frame runs a deferred call of the frame below
 }
 
 func NewFrame(cl *object.Closure, basePointer int) *Frame {
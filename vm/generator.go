@@ -0,0 +1,62 @@
+package vm
+
+import (
+	"fmt"
+	"gwine/object"
+)
+
+// generator returns the generator made by calling cl, a function that
+// yields, with args. It runs on a vm of its own, sharing the globals of this
+// one, whose frames and stack are left as they are at each yield and
+// resumed from there by the next call of Next. Errors it raises are traced
+// from where it was made.
+func (vm *VM) generator(cl *object.Closure, args []object.Object) *object.Generator {
+	g := vm.child()
+	g.stack[0] = cl
+	copy(g.stack[1:], args)
+	g.frames[1] = NewFrame(cl, 1)
+	g.frameIndex = 2
+	g.sp = 1 + cl.Fn.NumLocals
+	return &object.Generator{Resume: g.resume}
+}
+
+// child returns a vm to run a call made from this one, with the constants,
+// globals and streams of this one, tracing its errors from where it was
+// made.
+func (vm *VM) child() *VM {
+	c := &VM{
+		constants: vm.constants,
+		stack:     make([]object.Object, StackSize),
+		globals:   vm.globals,
+		frames:    make([]*Frame, MaxFrames),
+		streams:   vm.streams,
+		caller:    vm.trace(),
+	}
+	// frame 0 stands for the top level, with nothing to run once the call
+	// returns
+	c.frames[0] = NewFrame(&object.Closure{Fn: &object.CompiledFunction{}}, 0)
+	c.frameIndex = 1
+	return c
+}
+
+// resume runs a generator vm to its next yield, see object.Generator.
+func (vm *VM) resume() (object.Object, bool) {
+	vm.yielded = nil
+	if err := vm.Run(); err != nil {
+		exception := vm.exception(err)
+		return &object.Error{Message: exception.Error(), Exception: exception}, false
+	}
+	if vm.yielded == nil {
+		return nil, false
+	}
+	return vm.yielded, true
+}
+
+// raise returns the error a builtin or a generator returned as err, keeping
+// the exception it carries.
+func raise(err *object.Error) error {
+	if err.Exception != nil {
+		return err.Exception
+	}
+	return fmt.Errorf("%s", err.Message)
+}
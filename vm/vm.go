@@ -5,6 +5,7 @@ import (
 	"gwine/code"
 	"gwine/compiler"
 	"gwine/object"
+	"io"
 )
 
 const StackSize = 2048
@@ -17,14 +18,20 @@ type VM struct {
 	stack []object.Object
 	sp    int // offset of top object + 1 , 0 refers  stack empty
 
-	globals []object.Object
+	globals *Globals
 
 	frames     []*Frame
 	frameIndex int
+
+	streams *object.Streams
+
+	// set on the vms running generators, see generator
+	yielded object.Object
+	caller  []string
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, Handlers: bytecode.Handlers}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFm := NewFrame(mainClosure, 0)
 
@@ -37,14 +44,20 @@ func New(bytecode *compiler.Bytecode) *VM {
 		stack: make([]object.Object, StackSize),
 		sp:    0,
 
-		globals: make([]object.Object, GlobalsSize),
+		globals: NewGlobals(),
 
 		frames:     frames,
 		frameIndex: 1,
+		streams:    object.DefaultStreams(),
 	}
 }
-func NewWithGlobalStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+// SetStreams sets the input and output of the program, stdin and stdout by
+// default.
+func (vm *VM) SetStreams(in io.Reader, out io.Writer) {
+	vm.streams = object.NewStreams(in, out)
+}
+func NewWithGlobalStore(bytecode *compiler.Bytecode, s *Globals) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, Handlers: bytecode.Handlers}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFm := NewFrame(mainClosure, 0)
 
@@ -58,6 +71,7 @@ func NewWithGlobalStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
 		globals:    s,
 		frames:     frames,
 		frameIndex: 1,
+		streams:    object.DefaultStreams(),
 	}
 }
 func (vm *VM) Top() object.Object {
@@ -75,6 +89,15 @@ func (vm *VM) push(obj object.Object) error {
 	vm.sp++
 	return nil
 }
+
+// pushVariable pushes the value of a variable, which is nil if it is read
+// before it is set, as in let x = x + 1 with no x before.
+func (vm *VM) pushVariable(value object.Object) error {
+	if value == nil {
+		return fmt.Errorf("variable used before it is set")
+	}
+	return vm.push(value)
+}
 func (vm *VM) pop() object.Object {
 	if vm.sp == 0 {
 		return nil
@@ -101,7 +124,20 @@ func (vm *VM) popFrame() *Frame {
 	vm.frameIndex--
 	return vm.frames[vm.frameIndex]
 }
+// Run runs the program. An error stops it unless a try catches it, see
+// throw; an uncaught one is returned.
 func (vm *VM) Run() error {
+	for {
+		err := vm.run()
+		if err == nil {
+			return nil
+		}
+		if err = vm.throw(vm.exception(err)); err != nil {
+			return err
+		}
+	}
+}
+func (vm *VM) run() error {
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
@@ -180,12 +216,26 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpSlice:
+			step := vm.pop()
+			end := vm.pop()
+			start := vm.pop()
+			left := vm.pop()
+			result := object.Slice(left, start, end, step)
+			if err, ok := result.(*object.Error); ok {
+				return fmt.Errorf("%s", err.Message)
+			}
+			err := vm.push(result)
+			if err != nil {
+				return err
+			}
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
 			}
-		case code.OpEqual, code.OpNEqual, code.OpGT, code.OpLT:
+		case code.OpEqual, code.OpNEqual, code.OpGT, code.OpLT, code.OpGE, code.OpLE:
 			err := vm.executeComparison(op)
 			if err != nil {
 				return err
@@ -200,6 +250,20 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpBitNot:
+			err := vm.executeBitNotOperator()
+			if err != nil {
+				return err
+			}
+		case code.OpStringify:
+			value := vm.pop()
+			if _, ok := value.(*object.String); !ok {
+				value = &object.String{Value: value.Inspect()}
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
 		case code.OpPop:
 			vm.pop()
 		case code.OpTrue:
@@ -222,61 +286,186 @@ func (vm *VM) Run() error {
 			if !isTrue(condition) {
 				vm.currentFrame().ip = jumpto - 1
 			}
+		case code.OpJumpIfFalseOrPop, code.OpJumpIfTrueOrPop:
+			jumpto := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			if isTrue(vm.stack[vm.sp-1]) == (op == code.OpJumpIfTrueOrPop) {
+				vm.currentFrame().ip = jumpto - 1
+			} else {
+				vm.pop()
+			}
+		case code.OpJumpIfNotNullOrPop:
+			jumpto := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			if vm.stack[vm.sp-1] != object.NullObj {
+				vm.currentFrame().ip = jumpto - 1
+			} else {
+				vm.pop()
+			}
+		case code.OpJumpIfNull:
+			jumpto := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			if vm.stack[vm.sp-1] == object.NullObj {
+				vm.currentFrame().ip = jumpto - 1
+			}
 		case code.OpCall:
 			numArgs := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
-			callee := vm.stack[vm.sp-1-int(numArgs)]
-
-			switch callee := callee.(type) {
-			case *object.Closure:
-				if int(numArgs) != callee.Fn.NumParameters {
-					return fmt.Errorf("wrong number of argument")
-				}
-				frame := NewFrame(callee, vm.sp-int(numArgs))
-				vm.pushFrame(frame)
-				vm.sp = frame.basePointer + callee.Fn.NumLocals
-			case *object.Builtin:
-				args := vm.stack[vm.sp-int(numArgs) : vm.sp]
-				result := callee.Fn(args...)
-				vm.sp = vm.sp - int(numArgs) - 1
-				if result != nil {
-					err := vm.push(result)
-					if err != nil{
-						return nil
-					}
-				} else {
-					err := vm.push(object.NullObj)
-					if err != nil{
-						return nil
-					}
-				}
-			default:
-				return fmt.Errorf("calling non function or non builtin")
+			err := vm.call(int(numArgs))
+			if err != nil {
+				return err
+			}
+		case code.OpDefer:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			args := make([]object.Object, numArgs)
+			copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+			frame.defers = append(frame.defers, deferredCall{fn: vm.stack[vm.sp-numArgs-1], args: args})
+			vm.sp -= numArgs + 1
+		case code.OpYield:
+			vm.yielded = vm.pop()
+			return vm.push(object.NullObj)
+		case code.OpIter:
+			it := object.Iterate(vm.pop())
+			if err, ok := it.(*object.Error); ok {
+				return fmt.Errorf("%s", err.Message)
+			}
+			err := vm.push(it)
+			if err != nil {
+				return err
+			}
+		case code.OpSpawn:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+			fn := vm.stack[vm.sp-1-numArgs]
+			task := vm.spawn(fn, vm.stack[vm.sp-numArgs:vm.sp])
+			vm.sp = vm.sp - numArgs - 1
+			if err := vm.push(task); err != nil {
+				return err
+			}
+		case code.OpMatchLiteral:
+			literal := vm.pop()
+			value := vm.pop()
+			if err := vm.push(nativeBoolToBooleanObject(object.MatchLiteral(literal, value))); err != nil {
+				return err
+			}
+		case code.OpMatchArray:
+			min := int(code.ReadUint16(ins[ip+1:]))
+			n := int(code.ReadUint16(ins[ip+3:]))
+			rest := code.ReadUint8(ins[ip+5:]) == 1
+			vm.currentFrame().ip += 5
+			value := vm.pop()
+			if err := vm.push(nativeBoolToBooleanObject(object.MatchArray(value, min, n, rest))); err != nil {
+				return err
+			}
+		case code.OpMatchHash:
+			n := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			keys := make([]object.Object, n)
+			copy(keys, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			value := vm.pop()
+			if err := vm.push(nativeBoolToBooleanObject(object.MatchHash(value, keys))); err != nil {
+				return err
+			}
+		case code.OpNoMatch:
+			return raise(object.NoMatch(vm.pop()))
+		case code.OpDefault:
+			local := int(code.ReadUint8(ins[ip+1:]))
+			jumpto := int(code.ReadUint16(ins[ip+2:]))
+			vm.currentFrame().ip += 3
+			if vm.stack[vm.currentFrame().basePointer+local] != nil {
+				vm.currentFrame().ip = jumpto - 1
+			}
+		case code.OpSpread:
+			vm.stack[vm.sp-1] = &object.Spread{Value: vm.stack[vm.sp-1]}
+		case code.OpKeyword:
+			name := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.String)
+			vm.currentFrame().ip += 2
+			vm.stack[vm.sp-1] = &object.Keyword{Name: name.Value, Value: vm.stack[vm.sp-1]}
+		case code.OpMismatch:
+			pattern := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.String)
+			return raise(object.Mismatch(pattern.Value, vm.pop()))
+		case code.OpSelect:
+			numCases := int(code.ReadUint8(ins[ip+1:]))
+			hasDefault := code.ReadUint8(ins[ip+2:]) == 1
+			vm.currentFrame().ip += 2
+			cases := make([]object.SelectCase, numCases)
+			base := vm.sp - 3*numCases
+			for i := range cases {
+				c := vm.stack[base+3*i : base+3*i+3]
+				cases[i] = object.SelectCase{Channel: c[0], Value: c[1], Send: c[2] == object.True}
+			}
+			vm.sp = base
+			index, value := object.Select(vm.globals.tasks, cases, hasDefault)
+			if err, ok := value.(*object.Error); ok && index < 0 {
+				return raise(err)
+			}
+			if err := vm.push(value); err != nil {
+				return err
+			}
+			// on to the jump of the case chosen, each 3 bytes long
+			vm.currentFrame().ip += 3 * index
+		case code.OpIterNext:
+			jumpto := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			value, ok := vm.stack[vm.sp-1].(*object.Iterator).Next()
+			if err, isErr := value.(*object.Error); isErr {
+				return raise(err)
+			}
+			if !ok {
+				vm.pop()
+				vm.currentFrame().ip = jumpto - 1
+				break
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
 			}
 		case code.OpReturnValue:
 			rv := vm.pop()
-			frame := vm.popFrame()
-			vm.sp = frame.basePointer - 1
-
-			err := vm.push(rv)
+			err := vm.returnValue(rv)
 			if err != nil {
 				return err
 			}
 		case code.OpReturn:
-			frame := vm.popFrame()
-			vm.sp = frame.basePointer - 1
-			err := vm.push(object.NullObj)
+			err := vm.returnValue(object.NullObj)
+			if err != nil {
+				return err
+			}
+		case code.OpTry:
+			index := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			frame := vm.currentFrame()
+			frame.tries = append(frame.tries, tryBlock{handler: frame.cl.Fn.Handlers[index], sp: vm.sp})
+		case code.OpEndTry:
+			frame := vm.currentFrame()
+			frame.tries = frame.tries[:len(frame.tries)-1]
+		case code.OpEndFinally:
+			u, ok := vm.stack[vm.sp-1].(*unwinding)
+			if !ok {
+				// entered normally, the value of the try stays
+				break
+			}
+			vm.pop()
+			if u.exception != nil {
+				return u.exception
+			}
+			err := vm.returnValue(u.returnValue)
 			if err != nil {
 				return err
 			}
+		case code.OpThrow:
+			return object.NewException(vm.pop(), vm.trace())
 		case code.OpSetGlobal:
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
-			vm.globals[globalIndex] = vm.pop()
+			vm.setGlobal(globalIndex, vm.pop())
 		case code.OpGetGlobal:
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
-			err := vm.push(vm.globals[globalIndex])
+			err := vm.pushVariable(vm.getGlobal(globalIndex))
 			if err != nil {
 				return err
 			}
@@ -287,7 +476,7 @@ func (vm *VM) Run() error {
 		case code.OpGetLocal:
 			localIndex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
-			err := vm.push(vm.stack[vm.currentFrame().basePointer+int(localIndex)])
+			err := vm.pushVariable(vm.stack[vm.currentFrame().basePointer+int(localIndex)])
 			if err != nil {
 				return err
 			}
@@ -295,7 +484,7 @@ func (vm *VM) Run() error {
 			builtinIndex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 			def := object.Builtins[builtinIndex]
-			err := vm.push(def.Builtin)
+			err := vm.push(def)
 			if err != nil {
 				return nil
 			}
@@ -303,7 +492,7 @@ func (vm *VM) Run() error {
 			freeIndex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 			currentClosure := vm.currentFrame().cl
-			err := vm.push(currentClosure.Free[freeIndex])
+			err := vm.pushVariable(currentClosure.Free[freeIndex])
 			if err != nil{
 				return err
 			}
@@ -312,6 +501,52 @@ func (vm *VM) Run() error {
 	}
 	return nil
 }
+// call calls the function below the numArgs arguments on top of the stack:
+// a closure gets a new frame, a builtin runs and its result replaces them.
+func (vm *VM) call(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		args, err := callee.Fn.Signature.Bind(callee.Fn.Name, vm.stack[vm.sp-numArgs:vm.sp])
+		if err != nil {
+			return raise(err)
+		}
+		base := vm.sp - numArgs
+		if base+callee.Fn.NumLocals > StackSize {
+			return fmt.Errorf("stack overflow")
+		}
+		copy(vm.stack[base:], args)
+		numArgs = len(args)
+		vm.sp = base + numArgs
+		if callee.Fn.Generator {
+			g := vm.generator(callee, vm.stack[vm.sp-numArgs:vm.sp])
+			vm.sp = vm.sp - numArgs - 1
+			return vm.push(g)
+		}
+		// locals other than the parameters start unset, see pushVariable
+		for i := vm.sp; i < base+callee.Fn.NumLocals; i++ {
+			vm.stack[i] = nil
+		}
+		frame := NewFrame(callee, vm.sp-numArgs)
+		vm.pushFrame(frame)
+		vm.sp = frame.basePointer + callee.Fn.NumLocals
+	case *object.Builtin:
+		args := vm.stack[vm.sp-numArgs : vm.sp]
+		result := callee.Call(vm.streams, vm.globals.tasks, args...)
+		vm.sp = vm.sp - numArgs - 1
+		if err, ok := result.(*object.Error); ok {
+			return raise(err)
+		}
+		if result == nil {
+			result = object.NullObj
+		}
+		return vm.push(result)
+	default:
+		return fmt.Errorf("%s not a function", callee.Type())
+	}
+	return nil
+}
 func (vm *VM) buildArray(start, end int) object.Object {
 	eles := make([]object.Object, end-start)
 	for i := start; i < end; i++ {
@@ -320,30 +555,26 @@ func (vm *VM) buildArray(start, end int) object.Object {
 	return &object.Array{Elements: eles}
 }
 func (vm *VM) buildHash(start, end int) (object.Object, error) {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
 	for i := start; i < end; i += 2 {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
-		pair := object.HashPair{Key: key, Value: value}
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
 			return nil, fmt.Errorf("unusable as hash key %s", key.Type())
 		}
-		pairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey, value)
 	}
-	return &object.Hash{Pairs: pairs}, nil
+	return hash, nil
 }
 func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		array := left.(*object.Array)
-		index := index.(*object.Integer).Value
-		if index < 0 || int(index) > len(array.Elements)-1 {
-			return vm.push(object.NullObj)
-		}
-		return vm.push(array.Elements[index])
+		return vm.push(object.IndexArray(left.(*object.Array), index.(*object.Integer).Value))
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.push(object.IndexString(left.(*object.String), index.(*object.Integer).Value))
 	case left.Type() == object.HASH_OBJ:
 		hashmap := left.(*object.Hash)
 		key, ok := index.(object.Hashable)
@@ -351,11 +582,17 @@ func (vm *VM) executeIndexExpression(left, index object.Object) error {
 			return fmt.Errorf("unusable as hash key %s", index.Type())
 		}
 
-		pair, ok := hashmap.Pairs[key.HashKey()]
+		value, ok := hashmap.Get(key)
 		if !ok {
 			return vm.push(object.NullObj)
 		}
-		return vm.push(pair.Value)
+		return vm.push(value)
+	case left.Type() == object.EXCEPTION_OBJ:
+		value := object.IndexException(left.(*object.Exception), index)
+		if err, ok := value.(*object.Error); ok {
+			return fmt.Errorf("%s", err.Message)
+		}
+		return vm.push(value)
 	default:
 		return fmt.Errorf("index operator not supported %s", left.Type())
 	}
@@ -364,39 +601,33 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 
 	r := vm.pop()
 	l := vm.pop()
+	if l == nil || r == nil {
+		return fmt.Errorf("missing operand for %s", infixOperators[op])
+	}
 
 	switch {
-	case l.Type() == object.INTEGER_OBJ && r.Type() == object.INTEGER_OBJ:
+	case object.IsInteger(l) && object.IsInteger(r):
 		return vm.executeBinaryIntegerOperation(op, l, r)
 	case l.Type() == object.STRING_OBJ && r.Type() == object.STRING_OBJ:
 		return vm.executeBinaryStringOperation(op, l, r)
 	default:
-		return fmt.Errorf("operator %d operand %s , %s type dismatch", op, r.Type(), l.Type())
+		if l.Type() != r.Type() {
+			return fmt.Errorf("type mismatch: %s %s %s", l.Type(), infixOperators[op], r.Type())
+		}
+		return fmt.Errorf("unknown operator: %s %s %s", l.Type(), infixOperators[op], r.Type())
 	}
 }
 func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
-	lv := left.(*object.Integer).Value
-	rv := right.(*object.Integer).Value
-
-	var result int64
-	switch op {
-	case code.OpAdd:
-		result = lv + rv
-	case code.OpSub:
-		result = lv - rv
-	case code.OpMul:
-		result = lv * rv
-	case code.OpDiv:
-		result = lv / rv
-	default:
-		return fmt.Errorf("unknown operator %d", op)
+	result := object.IntegerInfix(infixOperators[op], left, right)
+	if err, ok := result.(*object.Error); ok {
+		return fmt.Errorf("%s", err.Message)
 	}
-	return vm.push(&object.Integer{Value: result})
+	return vm.push(result)
 }
 func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
 
 	if op != code.OpAdd {
-		return fmt.Errorf("unknown operator %d", op)
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), infixOperators[op], right.Type())
 	}
 	lv := left.(*object.String).Value
 	rv := right.(*object.String).Value
@@ -408,22 +639,24 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 
 	r := vm.pop()
 	l := vm.pop()
+	if l == nil || r == nil {
+		return fmt.Errorf("missing operand for %s", infixOperators[op])
+	}
 
-	if l.Type() == object.INTEGER_OBJ && r.Type() == object.INTEGER_OBJ {
-		lv := l.(*object.Integer).Value
-		rv := r.(*object.Integer).Value
+	if object.IsInteger(l) && object.IsInteger(r) {
+		return vm.executeBinaryIntegerOperation(op, l, r)
+	}
 
+	if l.Type() != r.Type() {
+		return fmt.Errorf("type mismatch: %s %s %s", l.Type(), infixOperators[op], r.Type())
+	}
+	if l.Type() == object.STRING_OBJ {
+		equal := l.(*object.String).Value == r.(*object.String).Value
 		switch op {
 		case code.OpEqual:
-			return vm.push(nativeBoolToBooleanObject(lv == rv))
+			return vm.push(nativeBoolToBooleanObject(equal))
 		case code.OpNEqual:
-			return vm.push(nativeBoolToBooleanObject(lv != rv))
-		case code.OpGT:
-			return vm.push(nativeBoolToBooleanObject(lv > rv))
-		case code.OpLT:
-			return vm.push(nativeBoolToBooleanObject(lv < rv))
-		default:
-			return fmt.Errorf("unknown operator %d", op)
+			return vm.push(nativeBoolToBooleanObject(!equal))
 		}
 	}
 
@@ -433,9 +666,30 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	case code.OpNEqual:
 		return vm.push(nativeBoolToBooleanObject(l != r))
 	default:
-		return fmt.Errorf("unknown operator %d", op)
+		return fmt.Errorf("unknown operator: %s %s %s", l.Type(), infixOperators[op], r.Type())
 	}
 }
+
+// infixOperators are the gwine operators of the infix opcodes.
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:    "+",
+	code.OpSub:    "-",
+	code.OpMul:    "*",
+	code.OpDiv:    "/",
+	code.OpMod:    "%",
+	code.OpPow:    "**",
+	code.OpBitAnd: "&",
+	code.OpBitOr:  "|",
+	code.OpBitXor: "^",
+	code.OpShl:    "<<",
+	code.OpShr:    ">>",
+	code.OpEqual:  "==",
+	code.OpNEqual: "!=",
+	code.OpGT:     ">",
+	code.OpLT:     "<",
+	code.OpGE:     ">=",
+	code.OpLE:     "<=",
+}
 func (vm *VM) executeBangOperator() error {
 	operand := vm.pop()
 
@@ -450,13 +704,19 @@ func (vm *VM) executeBangOperator() error {
 		return vm.push(object.False)
 	}
 }
+func (vm *VM) executeBitNotOperator() error {
+	operand := vm.pop()
+	if !object.IsInteger(operand) {
+		return fmt.Errorf("unknown operand: ~%s", operand.Type())
+	}
+	return vm.push(object.IntegerPrefix("~", operand))
+}
 func (vm *VM) executeMinusOperator() error {
 	operand := vm.pop()
-	if operand.Type() != object.INTEGER_OBJ {
-		return fmt.Errorf("unsupported type %s for -", operand.Type())
+	if !object.IsInteger(operand) {
+		return fmt.Errorf("unknown operand: -%s", operand.Type())
 	}
-	v := operand.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -v})
+	return vm.push(object.IntegerPrefix("-", operand))
 }
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
@@ -1,18 +1,23 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
 	"gwine/compiler"
 	"gwine/lexer"
+	"gwine/module"
 	"gwine/object"
 	"gwine/parser"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestArrayinHash(t *testing.T) {
 
 	constants := []object.Object{}
-	globals := make([]object.Object, GlobalsSize)
+	globals := NewGlobals()
 	symboltbl := compiler.NewSymbolTable()
 	for i, v := range object.Builtins {
 		symboltbl.DefineBuiltin(i, v.Name)
@@ -44,3 +49,198 @@ func TestArrayinHash(t *testing.T) {
 	fmt.Println(vmm.LastPoped().Inspect()+"\n")
 
 }
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"util.gw": `export let double = fn(x) { x * 2 };`,
+		"lib.gw": `import "util.gw" as util;
+			export let add = fn(a, b) { a + b };
+			export let twice = fn(x) { util.double(x) };
+			let hidden = 1;`,
+		"a.gw": `import "b.gw" as b;`,
+		"b.gw": `import "a.gw" as a;`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(input string) (object.Object, error) {
+		symboltbl := compiler.NewSymbolTable()
+		for i, v := range object.Builtins {
+			symboltbl.DefineBuiltin(i, v.Name)
+		}
+		comp := compiler.NewWithState(symboltbl, []object.Object{})
+		comp.SetLoader(module.NewLoader(), filepath.Join(dir, "main.gw"))
+		program := parser.New(lexer.New(input)).ParseProgram()
+		if err := comp.Compile(program); err != nil {
+			return nil, err
+		}
+		vmm := NewWithGlobalStore(comp.ByteCode(), NewGlobals())
+		if err := vmm.Run(); err != nil {
+			return nil, err
+		}
+		return vmm.LastPoped(), nil
+	}
+
+	result, err := run(`import "lib.gw" as lib;
+		import { add } from "lib.gw";
+		let hidden = 10;
+		lib.twice(add(1, 2)) + hidden`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := result.(*object.Integer); !ok || got.Value != 16 {
+		t.Errorf("result = %v, want 16", result)
+	}
+
+	errors := map[string]string{
+		`import "lib.gw" as lib; lib.hidden`:     "module lib does not export hidden",
+		`import { hidden } from "lib.gw";`:       "lib.gw does not export hidden",
+		`import "lib.gw" as lib; lib`:            "module lib can only be used as lib.name",
+		`import "a.gw" as a;`:                    "import cycle:",
+		`let f = fn() { import "lib.gw" as l; }`: "import is only allowed at the top level",
+	}
+	for input, want := range errors {
+		_, err := run(input)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	symboltbl := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symboltbl.DefineBuiltin(i, v.Name)
+	}
+	comp := compiler.NewWithState(symboltbl, []object.Object{})
+	program := parser.New(lexer.New(`
+		let name = input("name? ");
+		puts("hello", name, [1, 2]);
+		print("a", "b");
+		printf("|%3d|%s|", 7, read_line());
+		read_line()`)).ParseProgram()
+	if err := comp.Compile(program); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	vmm := NewWithGlobalStore(comp.ByteCode(), NewGlobals())
+	vmm.SetStreams(strings.NewReader("gwine\r\nlast"), &out)
+	if err := vmm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "name? hello gwine [1,2]\na b|  7|last|"
+	if out.String() != expected {
+		t.Errorf("output = %q, want %q", out.String(), expected)
+	}
+	if vmm.LastPoped() != object.NullObj {
+		t.Errorf("read_line at end of input = %v, want null", vmm.LastPoped())
+	}
+}
+
+// TestSpawn is meant for the race detector too: tasks read globals and
+// write output while the top level goes on redefining them.
+func TestSpawn(t *testing.T) {
+	symboltbl := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symboltbl.DefineBuiltin(i, v.Name)
+	}
+	comp := compiler.NewWithState(symboltbl, []object.Object{})
+	program := parser.New(lexer.New(`
+		let n = 0;
+		let read = fn(i) { print(i, ""); n + i };
+		let tasks = [];
+		for (i in [1, 2, 3, 4, 5, 6, 7, 8]) {
+			let tasks = push(tasks, spawn read(i));
+			let n = n + 1;
+		}
+		wait_all(tasks)`)).ParseProgram()
+	if err := comp.Compile(program); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	vmm := NewWithGlobalStore(comp.ByteCode(), NewGlobals())
+	vmm.SetStreams(strings.NewReader(""), &out)
+	if err := vmm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// tasks may see n as it was at any time after they were spawned
+	results := vmm.LastPoped().(*object.Array).Elements
+	for i, result := range results {
+		if n := result.(*object.Integer).Value - int64(i+1); n < int64(i) || n > 8 {
+			t.Errorf("task %d saw n = %d", i+1, n)
+		}
+	}
+	if len(results) != 8 {
+		t.Errorf("results = %s", vmm.LastPoped().Inspect())
+	}
+	if out.Len() != 16 {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestConst(t *testing.T) {
+	compile := func(input string) (*compiler.Compiler, error) {
+		symboltbl := compiler.NewSymbolTable()
+		for i, v := range object.Builtins {
+			symboltbl.DefineBuiltin(i, v.Name)
+		}
+		comp := compiler.NewWithState(symboltbl, []object.Object{})
+		return comp, comp.Compile(parser.New(lexer.New(input)).ParseProgram())
+	}
+
+	comp, err := compile(`const N = 5;
+		const H = {"n": N};
+		let f = fn() { let g = fn() { N + H["n"] }; g() };
+		f()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vmm := NewWithGlobalStore(comp.ByteCode(), NewGlobals())
+	if err := vmm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := vmm.LastPoped().(*object.Integer); !ok || got.Value != 10 {
+		t.Errorf("result = %v, want 10", vmm.LastPoped())
+	}
+	// N is a literal, so the functions use its value rather than the global;
+	// H is not, so g still loads it
+	for _, c := range comp.ByteCode().Constants {
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		if n := strings.Count(fn.Instructions.String(), "OpGetGlobal"); n > 1 {
+			t.Errorf("N not inlined in\n%s", fn.Instructions)
+		}
+	}
+
+	errors := []string{
+		`const N = 1; let N = 2;`,
+		`const N = 1; const N = 2;`,
+		`const N = 1; let [a, N] = [1, 2];`,
+		`const N = 1; for (N in [1]) { N }`,
+		`const N = 1; match (2) { [..N] => N, N => N }`,
+		`const N = 1; try { throw 1 } catch (N) { N }`,
+		`let f = fn() { const N = 1; select { case N = recv(channel(1)) { N } default { 0 } } }`,
+	}
+	for _, input := range errors {
+		if _, err := compile(input); err == nil || !strings.Contains(err.Error(), "cannot reassign constant N") {
+			t.Errorf("%s: error = %v, want cannot reassign constant N", input, err)
+		}
+	}
+	for _, input := range []string{
+		`const N = 1; let f = fn(N) { let N = 2; N };`,
+		`let N = 1; const N = 2;`,
+		`for (i in [1, 2]) { const N = i; N }`,
+	} {
+		if _, err := compile(input); err != nil {
+			t.Errorf("%s: error = %v", input, err)
+		}
+	}
+}